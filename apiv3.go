@@ -0,0 +1,339 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const (
+	// API 3.0 base URL, used with Tencent Cloud API keys
+	v3BaseURL = "https://dnspod.tencentcloudapi.com"
+
+	// v3APIVersion is the X-TC-Version of the DNSPod API 3.0 actions
+	v3APIVersion = "2021-03-23"
+
+	// v3PageSize is the largest page API 3.0 returns for list actions
+	v3PageSize = 3000
+)
+
+// v3Actions maps legacy API actions to their API 3.0 equivalents
+var v3Actions = map[string]string{
+	"Domain.List":   "DescribeDomainList",
+	"Domain.Info":   "DescribeDomain",
+	"Record.List":   "DescribeRecordList",
+	"Record.Info":   "DescribeRecord",
+	"Record.Create": "CreateRecord",
+	"Record.Modify": "ModifyRecord",
+	"Record.Ddns":   "ModifyDynamicDNS",
+	"Record.Remove": "DeleteRecord",
+	"Record.Remark": "ModifyRecordRemark",
+}
+
+// v3Param is the API 3.0 name of a legacy request parameter
+type v3Param struct {
+	name    string
+	numeric bool
+}
+
+// v3Params maps legacy request parameters to API 3.0 ones. Parameters
+// common to all legacy requests are not listed; they have no equivalent.
+var v3Params = map[string]v3Param{
+	"domain_id":      {"DomainId", true},
+	"record_id":      {"RecordId", true},
+	"sub_domain":     {"SubDomain", false},
+	"record_type":    {"RecordType", false},
+	"record_line":    {"RecordLine", false},
+	"record_line_id": {"RecordLineId", false},
+	"value":          {"Value", false},
+	"mx":             {"MX", true},
+	"ttl":            {"TTL", true},
+	"weight":         {"Weight", true},
+	"status":         {"Status", false},
+	"remark":         {"Remark", false},
+	"offset":         {"Offset", true},
+	"length":         {"Limit", true},
+	"keyword":        {"Keyword", false},
+	"group_id":       {"GroupId", true},
+	"type":           {"Type", false},
+}
+
+// commonParams are sent with every legacy request and dropped for API 3.0
+var commonParams = map[string]bool{
+	"login_token":    true,
+	"format":         true,
+	"error_on_empty": true,
+	"lang":           true,
+}
+
+// v3DomainTypes maps the legacy Domain.List type filter to API 3.0
+var v3DomainTypes = map[string]string{
+	"all":     "ALL",
+	"mine":    "MINE",
+	"share":   "SHARE",
+	"ispause": "PAUSE",
+	"vip":     "VIP",
+}
+
+// v3Error is the error of a failed API 3.0 call
+type v3Error struct {
+	Code    string `json:"Code"`
+	Message string `json:"Message"`
+}
+
+// v3Request converts the parameters of a legacy action to an API 3.0
+// request payload
+func v3Request(action string, form url.Values) (map[string]any, error) {
+	payload := make(map[string]any)
+	for key := range form {
+		if commonParams[key] {
+			continue
+		}
+		param, ok := v3Params[key]
+		if !ok {
+			return nil, fmt.Errorf("parameter %s of %s is not supported by API 3.0", key, action)
+		}
+
+		value := form.Get(key)
+		switch {
+		case action == "Record.List" && key == "sub_domain":
+			// The filter is spelled differently from the record field
+			param.name = "Subdomain"
+		case key == "status":
+			value = strings.ToUpper(value)
+		case key == "type":
+			typ, ok := v3DomainTypes[value]
+			if !ok {
+				return nil, fmt.Errorf("domain type %s is not supported by API 3.0", value)
+			}
+			value = typ
+		}
+
+		if !param.numeric {
+			payload[param.name] = value
+			continue
+		}
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %q", key, value)
+		}
+		payload[param.name] = n
+	}
+
+	switch action {
+	case "Domain.List", "Record.List":
+		if _, ok := payload["Limit"]; !ok {
+			payload["Limit"] = v3PageSize
+		}
+	}
+	return payload, nil
+}
+
+// v3Response converts the response of an API 3.0 call to the legacy
+// response of action
+func v3Response(action string, data json.RawMessage) (any, error) {
+	switch action {
+	case "Domain.List":
+		var resp struct {
+			DomainCountInfo struct {
+				DomainTotal int `json:"DomainTotal"`
+			} `json:"DomainCountInfo"`
+			DomainList []struct {
+				DomainID    int64  `json:"DomainId"`
+				Name        string `json:"Name"`
+				Status      string `json:"Status"`
+				Grade       string `json:"Grade"`
+				GroupID     int64  `json:"GroupId"`
+				RecordCount int64  `json:"RecordCount"`
+			} `json:"DomainList"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+
+		out := domainListResponse{Domains: make([]domain, 0, len(resp.DomainList))}
+		out.Info.DomainTotal = resp.DomainCountInfo.DomainTotal
+		for _, d := range resp.DomainList {
+			out.Domains = append(out.Domains, domain{
+				ID:      json.Number(strconv.FormatInt(d.DomainID, 10)),
+				Name:    d.Name,
+				Status:  strings.ToLower(d.Status),
+				Grade:   d.Grade,
+				GroupID: strconv.FormatInt(d.GroupID, 10),
+				Records: strconv.FormatInt(d.RecordCount, 10),
+			})
+		}
+		out.apiResponse = legacyOK()
+		return out, nil
+
+	case "Domain.Info":
+		var resp struct {
+			DomainInfo struct {
+				DomainID     int64    `json:"DomainId"`
+				Domain       string   `json:"Domain"`
+				Status       string   `json:"Status"`
+				Grade        string   `json:"Grade"`
+				DnspodNsList []string `json:"DnspodNsList"`
+				UpdatedOn    string   `json:"UpdatedOn"`
+			} `json:"DomainInfo"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+
+		d := resp.DomainInfo
+		out := domainInfoResponse{Domain: domainInfo{
+			ID:        json.Number(strconv.FormatInt(d.DomainID, 10)),
+			Name:      d.Domain,
+			Status:    strings.ToLower(d.Status),
+			Grade:     d.Grade,
+			DNSPodNS:  d.DnspodNsList,
+			UpdatedOn: d.UpdatedOn,
+		}}
+		out.apiResponse = legacyOK()
+		return out, nil
+
+	case "Record.List":
+		var resp struct {
+			RecordCountInfo struct {
+				TotalCount int64 `json:"TotalCount"`
+			} `json:"RecordCountInfo"`
+			RecordList []struct {
+				RecordID  int64  `json:"RecordId"`
+				Value     string `json:"Value"`
+				Status    string `json:"Status"`
+				UpdatedOn string `json:"UpdatedOn"`
+				Name      string `json:"Name"`
+				Line      string `json:"Line"`
+				LineID    string `json:"LineId"`
+				Type      string `json:"Type"`
+				Weight    *int64 `json:"Weight"`
+				Remark    string `json:"Remark"`
+				TTL       int64  `json:"TTL"`
+				MX        int64  `json:"MX"`
+			} `json:"RecordList"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+
+		out := recordListResponse{Records: make([]record, 0, len(resp.RecordList))}
+		out.Info.RecordTotal = json.Number(strconv.FormatInt(resp.RecordCountInfo.TotalCount, 10))
+		for _, r := range resp.RecordList {
+			rec := record{
+				ID:        strconv.FormatInt(r.RecordID, 10),
+				TTL:       strconv.FormatInt(r.TTL, 10),
+				Value:     r.Value,
+				Enabled:   v3Enabled(r.Status == "ENABLE"),
+				Status:    strings.ToLower(r.Status),
+				UpdatedOn: r.UpdatedOn,
+				Name:      r.Name,
+				Line:      r.Line,
+				LineID:    r.LineID,
+				Type:      r.Type,
+				Remark:    r.Remark,
+			}
+			if r.MX != 0 {
+				rec.MX = strconv.FormatInt(r.MX, 10)
+			}
+			if r.Weight != nil {
+				rec.Weight = strconv.FormatInt(*r.Weight, 10)
+			}
+			out.Records = append(out.Records, rec)
+		}
+		out.apiResponse = legacyOK()
+		return out, nil
+
+	case "Record.Info":
+		var resp struct {
+			RecordInfo struct {
+				ID           int64  `json:"Id"`
+				SubDomain    string `json:"SubDomain"`
+				RecordType   string `json:"RecordType"`
+				RecordLine   string `json:"RecordLine"`
+				RecordLineID string `json:"RecordLineId"`
+				Value        string `json:"Value"`
+				Weight       *int64 `json:"Weight"`
+				MX           int64  `json:"MX"`
+				TTL          int64  `json:"TTL"`
+				Enabled      int64  `json:"Enabled"`
+				Remark       string `json:"Remark"`
+				UpdatedOn    string `json:"UpdatedOn"`
+			} `json:"RecordInfo"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+
+		r := resp.RecordInfo
+		out := recordInfoResponse{Record: recordInfo{
+			ID:           strconv.FormatInt(r.ID, 10),
+			SubDomain:    r.SubDomain,
+			RecordType:   r.RecordType,
+			RecordLine:   r.RecordLine,
+			RecordLineID: r.RecordLineID,
+			Value:        r.Value,
+			MX:           strconv.FormatInt(r.MX, 10),
+			TTL:          strconv.FormatInt(r.TTL, 10),
+			Enabled:      v3Enabled(r.Enabled == 1),
+			Remark:       r.Remark,
+			UpdatedOn:    r.UpdatedOn,
+		}}
+		if r.Weight != nil {
+			out.Record.Weight = json.RawMessage(strconv.FormatInt(*r.Weight, 10))
+		}
+		out.apiResponse = legacyOK()
+		return out, nil
+
+	case "Record.Create", "Record.Modify", "Record.Ddns":
+		var resp struct {
+			RecordID int64 `json:"RecordId"`
+		}
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, err
+		}
+
+		out := recordResponse{Record: record{ID: strconv.FormatInt(resp.RecordID, 10)}}
+		out.apiResponse = legacyOK()
+		return out, nil
+	}
+
+	return legacyOK(), nil
+}
+
+// legacyOK returns the status of a successful legacy response
+func legacyOK() apiResponse {
+	var out apiResponse
+	out.Status.Code = successCode
+	out.Status.Message = "Action completed successful"
+	return out
+}
+
+// legacyError returns the legacy response for a failed API 3.0 call.
+// Frequency limits get the legacy code, so that adaptive throttling sees
+// them; other codes are passed through.
+func legacyError(e v3Error) (apiResponse, int) {
+	var out apiResponse
+	out.Status.Code = e.Code
+	out.Status.Message = e.Message
+
+	switch {
+	case e.Code == "InvalidAction":
+		return out, http.StatusNotFound
+	case strings.HasPrefix(e.Code, "RequestLimitExceeded"):
+		out.Status.Code = frequencyLimitCode
+		out.Status.Message = e.Code + ": " + e.Message
+	}
+	return out, http.StatusOK
+}
+
+// v3Enabled formats a record's enabled state the legacy way
+func v3Enabled(enabled bool) string {
+	if enabled {
+		return "1"
+	}
+	return "0"
+}
@@ -0,0 +1,97 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// newV3Server serves API 3.0 calls with handle, which gets the action and
+// payload and returns the Response object
+func newV3Server(t *testing.T, handle func(action string, payload map[string]any) any) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			t.Errorf("unsigned %s request", r.Header.Get("X-TC-Action"))
+		}
+		var payload map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("invalid payload: %v", err)
+		}
+		json.NewEncoder(w).Encode(map[string]any{"Response": handle(r.Header.Get("X-TC-Action"), payload)})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// v3DomainList is a DescribeDomainList response for the given domains
+func v3DomainList(domains ...map[string]any) any {
+	return map[string]any{
+		"DomainCountInfo": map[string]any{"DomainTotal": len(domains)},
+		"DomainList":      domains,
+	}
+}
+
+func TestV3ResponseDomainInfo(t *testing.T) {
+	data := json.RawMessage(`{
+		"DomainInfo": {
+			"DomainId": 42,
+			"Domain": "example.com",
+			"Status": "ENABLE",
+			"Grade": "DP_FREE",
+			"DnspodNsList": ["a.dnspod.net", "b.dnspod.net"],
+			"ActualNsList": ["ns1.other.net"],
+			"UpdatedOn": "2024-01-02 03:04:05"
+		},
+		"RequestId": "x"
+	}`)
+
+	out, err := v3Response("Domain.Info", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info := out.(domainInfoResponse).Domain
+	want := domainInfo{
+		ID:        "42",
+		Name:      "example.com",
+		Status:    "enable",
+		Grade:     "DP_FREE",
+		DNSPodNS:  []string{"a.dnspod.net", "b.dnspod.net"},
+		UpdatedOn: "2024-01-02 03:04:05",
+	}
+	if !reflect.DeepEqual(info, want) {
+		t.Errorf("got %+v, want %+v", info, want)
+	}
+}
+
+func TestGetZoneNameServersV3(t *testing.T) {
+	server := newV3Server(t, func(action string, payload map[string]any) any {
+		switch action {
+		case "DescribeDomainList":
+			return v3DomainList(map[string]any{"DomainId": 42, "Name": "example.com", "Status": "ENABLE"})
+		case "DescribeDomain":
+			if payload["Domain"] != "example.com" {
+				t.Errorf("DescribeDomain for %v", payload["Domain"])
+			}
+			return map[string]any{"DomainInfo": map[string]any{
+				"DomainId":     42,
+				"Domain":       "example.com",
+				"DnspodNsList": []string{"A.dnspod.net.", "b.dnspod.net"},
+			}}
+		}
+		t.Errorf("unexpected action %s", action)
+		return map[string]any{"Error": map[string]string{"Code": "InvalidAction", "Message": action}}
+	})
+
+	p := &Provider{SecretID: "id", SecretKey: "key", Endpoint: server.URL}
+	got, err := p.GetZoneNameServers(context.Background(), "example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a.dnspod.net.", "b.dnspod.net."}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
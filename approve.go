@@ -0,0 +1,66 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrPlanRejected is returned by ApplyPlan when the approver rejects a plan
+var ErrPlanRejected = errors.New("plan was not approved")
+
+// ErrPlanChanged is returned by Sync, Purge and ReplaceValue when the zone
+// changed while the plan was waiting for approval, so that the approved
+// plan no longer applies. Nothing is changed; the operation can be retried.
+var ErrPlanChanged = errors.New("zone changed while the plan was awaiting approval")
+
+// Approver decides whether a plan may be applied. It is consulted between
+// planning and applying, so that the sync engine can be embedded in CI/CD
+// pipelines with policy control. Approve is called without holding the
+// zone lock, so it may block, for example to wait for a manual decision.
+type Approver interface {
+	Approve(ctx context.Context, plan *Plan) (bool, error)
+}
+
+// ApproverFunc adapts a function to the Approver interface
+type ApproverFunc func(ctx context.Context, plan *Plan) (bool, error)
+
+// Approve implements Approver
+func (f ApproverFunc) Approve(ctx context.Context, plan *Plan) (bool, error) {
+	return f(ctx, plan)
+}
+
+// PolicyApprover auto-approves small plans and defers everything else to a
+// manual approver
+type PolicyApprover struct {
+	// MaxAutoChanges is the largest number of changes approved without
+	// asking Manual. Zero means no plan is auto-approved.
+	MaxAutoChanges int
+
+	// AutoApproveDeletes allows plans containing deletes to be
+	// auto-approved. By default any delete requires manual approval.
+	AutoApproveDeletes bool
+
+	// Manual is asked about plans that are not auto-approved. If nil, those
+	// plans are rejected.
+	Manual Approver
+}
+
+// Approve implements Approver
+func (a PolicyApprover) Approve(ctx context.Context, plan *Plan) (bool, error) {
+	s := plan.summary()
+	small := len(plan.Changes) <= a.MaxAutoChanges
+	if small && (s.Delete == 0 || a.AutoApproveDeletes) {
+		return true, nil
+	}
+
+	if a.Manual == nil {
+		return false, nil
+	}
+	return a.Manual.Approve(ctx, plan)
+}
+
+// Interface guards
+var (
+	_ Approver = ApproverFunc(nil)
+	_ Approver = PolicyApprover{}
+)
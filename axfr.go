@@ -0,0 +1,189 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// AXFRImportOptions configures ImportAXFR
+type AXFRImportOptions struct {
+	// Server is the address (host:port) of the nameserver to transfer the
+	// zone from. The port defaults to 53.
+	Server string
+
+	// SourceZone is the zone name requested from the server. It defaults to
+	// the DNSPod zone being imported into.
+	SourceZone string
+
+	// TSIGName, TSIGSecret (base64) and TSIGAlgorithm authenticate the
+	// transfer. TSIGAlgorithm defaults to hmac-sha256.
+	TSIGName      string
+	TSIGSecret    string
+	TSIGAlgorithm string
+
+	// IncludeApexNS keeps the source zone's apex NS records. They are
+	// skipped by default because DNSPod manages its own apex NS records.
+	IncludeApexNS bool
+
+	// DryRun converts the transferred records without creating them.
+	DryRun bool
+
+	// Timeout bounds dialing and each read during the transfer. It
+	// defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+// ImportAXFR performs a zone transfer from a nameserver the caller controls,
+// converts the resource records and creates them in the DNSPod zone. SOA and
+// DNSSEC records are always skipped since DNSPod generates its own. It
+// returns the records that were created, or the converted records if
+// DryRun is set.
+func (p *Provider) ImportAXFR(ctx context.Context, zone string, opts AXFRImportOptions) ([]libdns.Record, error) {
+	records, err := transferZone(ctx, zone, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun || len(records) == 0 {
+		return records, nil
+	}
+
+	created, err := p.AppendRecords(ctx, zone, records)
+	if err != nil {
+		return created, fmt.Errorf("failed to import records into zone %s: %w", zone, err)
+	}
+
+	return created, nil
+}
+
+// transferZone runs the AXFR and converts the answer to libdns records
+func transferZone(ctx context.Context, zone string, opts AXFRImportOptions) ([]libdns.Record, error) {
+	if opts.Server == "" {
+		return nil, fmt.Errorf("AXFR source server is required")
+	}
+
+	server := opts.Server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+
+	sourceZone := dns.Fqdn(strings.ToLower(opts.SourceZone))
+	if opts.SourceZone == "" {
+		sourceZone = dns.Fqdn(strings.ToLower(zone))
+	}
+
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+
+	msg := new(dns.Msg)
+	msg.SetAxfr(sourceZone)
+
+	transfer := &dns.Transfer{
+		DialTimeout: timeout,
+		ReadTimeout: timeout,
+	}
+
+	if opts.TSIGName != "" {
+		algorithm := opts.TSIGAlgorithm
+		if algorithm == "" {
+			algorithm = dns.HmacSHA256
+		}
+		keyName := dns.Fqdn(strings.ToLower(opts.TSIGName))
+		transfer.TsigSecret = map[string]string{keyName: opts.TSIGSecret}
+		msg.SetTsig(keyName, dns.Fqdn(algorithm), 300, time.Now().Unix())
+	}
+
+	envelopes, err := transfer.In(msg, server)
+	if err != nil {
+		if transfer.Conn != nil {
+			transfer.Close()
+		}
+		return nil, fmt.Errorf("failed to start zone transfer from %s: %w", server, err)
+	}
+
+	// If the transfer is abandoned, close the connection so that the
+	// transfer goroutine fails at its next read, and drain what it still
+	// sends so that it can exit
+	finished := false
+	defer func() {
+		if !finished {
+			transfer.Close()
+			go func() {
+				for range envelopes {
+				}
+			}()
+		}
+	}()
+
+	var records []libdns.Record
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case env, ok := <-envelopes:
+			if !ok {
+				finished = true
+				return records, nil
+			}
+			if env.Error != nil {
+				return nil, fmt.Errorf("zone transfer from %s failed: %w", server, env.Error)
+			}
+			for _, rr := range env.RR {
+				rec, ok, err := convertTransferredRR(rr, sourceZone, zone, opts.IncludeApexNS)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					records = append(records, rec)
+				}
+			}
+		}
+	}
+}
+
+// convertTransferredRR converts a transferred RR into a libdns record named
+// within the destination zone. It reports false for records that are skipped.
+func convertTransferredRR(rr dns.RR, sourceZone, zone string, includeApexNS bool) (libdns.Record, bool, error) {
+	hdr := rr.Header()
+
+	switch hdr.Rrtype {
+	case dns.TypeSOA, dns.TypeRRSIG, dns.TypeNSEC, dns.TypeNSEC3,
+		dns.TypeNSEC3PARAM, dns.TypeDNSKEY, dns.TypeCDS, dns.TypeCDNSKEY:
+		return nil, false, nil
+	}
+
+	owner := strings.ToLower(hdr.Name)
+	if hdr.Rrtype == dns.TypeNS && owner == sourceZone && !includeApexNS {
+		return nil, false, nil
+	}
+
+	var data string
+	if txt, ok := rr.(*dns.TXT); ok {
+		data = strings.Join(txt.Txt, "")
+	} else {
+		data = strings.TrimSpace(strings.TrimPrefix(rr.String(), hdr.String()))
+	}
+
+	rel := libdns.RelativeName(owner, sourceZone)
+	libRR := libdns.RR{
+		Name: makeAbsoluteName(rel, zone),
+		Type: dns.TypeToString[hdr.Rrtype],
+		TTL:  time.Duration(hdr.Ttl) * time.Second,
+		Data: data,
+	}
+
+	rec, err := libRR.Parse()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to convert transferred record %s %s: %w", hdr.Name, libRR.Type, err)
+	}
+
+	return rec, true, nil
+}
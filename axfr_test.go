@@ -0,0 +1,65 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+	"net"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// transferGoroutines counts the running zone transfer goroutines
+func transferGoroutines() int {
+	buf := make([]byte, 1<<20)
+	return strings.Count(string(buf[:runtime.Stack(buf, true)]), "(*Transfer).inAxfr")
+}
+
+func TestTransferZoneCancel(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	release := make(chan struct{})
+	server := &dns.Server{Listener: listener, Handler: dns.HandlerFunc(func(w dns.ResponseWriter, r *dns.Msg) {
+		soa, _ := dns.NewRR("example.com. 600 IN SOA ns1.example.com. admin.example.com. 1 7200 3600 1209600 600")
+		a, _ := dns.NewRR("www.example.com. 600 IN A 192.0.2.1")
+
+		// Send the start of the zone and then stall
+		ch := make(chan *dns.Envelope)
+		go (&dns.Transfer{}).Out(w, r, ch)
+		ch <- &dns.Envelope{RR: []dns.RR{soa, a}}
+		<-release
+		close(ch)
+	})}
+	go server.ActivateAndServe()
+	t.Cleanup(func() {
+		close(release)
+		server.Shutdown()
+	})
+
+	before := transferGoroutines()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = transferZone(ctx, "example.com", AXFRImportOptions{Server: listener.Addr().String()})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want the context error", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("returned after %v", elapsed)
+	}
+
+	// The transfer goroutine exits once its connection is closed
+	deadline := time.Now().Add(2 * time.Second)
+	for transferGoroutines() > before {
+		if time.Now().After(deadline) {
+			t.Fatal("zone transfer goroutine is still running")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
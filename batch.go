@@ -0,0 +1,485 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// minBatchSize is the number of writes from which the Batch.* actions
+	// are used instead of one request per record
+	minBatchSize = 2
+
+	// batchPollInterval is how often Batch.Detail is polled while a batch
+	// job is running
+	batchPollInterval = time.Second
+)
+
+// batchRecord is a record in the records parameter of Batch.Record.Create
+type batchRecord struct {
+	SubDomain  string `json:"sub_domain"`
+	RecordType string `json:"record_type"`
+	RecordLine string `json:"record_line"`
+	Value      string `json:"value"`
+	TTL        string `json:"ttl,omitempty"`
+	MX         string `json:"mx,omitempty"`
+}
+
+// batchResult is the outcome of one record of a batch job
+type batchResult struct {
+	ID         string `json:"id"`
+	SubDomain  string `json:"sub_domain"`
+	RecordType string `json:"record_type"`
+	Value      string `json:"value"`
+	Status     string `json:"status"`
+	ErrMsg     string `json:"err_msg"`
+}
+
+type batchResponse struct {
+	apiResponse
+	JobID  string `json:"job_id"`
+	Detail []struct {
+		Records []batchResult `json:"records"`
+	} `json:"detail"`
+}
+
+// results returns the outcomes of all records of the job
+func (r *batchResponse) results() []batchResult {
+	var results []batchResult
+	for _, detail := range r.Detail {
+		results = append(results, detail.Records...)
+	}
+	return results
+}
+
+// batchCreate creates records with Batch.Record.Create and waits for the
+// job to finish. The results are in no particular order.
+func (c *Client) batchCreate(ctx context.Context, domainID string, recs []record) ([]batchResult, error) {
+	records := make([]batchRecord, len(recs))
+	for i, rec := range recs {
+		params := make(map[string]string)
+		c.lineParams(params, rec)
+
+		ttl := rec.TTL
+		if ttl == "" {
+			ttl = "600" // Default TTL
+		}
+		records[i] = batchRecord{
+			SubDomain:  rec.Name,
+			RecordType: rec.Type,
+			RecordLine: params["record_line"],
+			Value:      rec.Value,
+			TTL:        ttl,
+			MX:         rec.MX,
+		}
+	}
+
+	data, err := json.Marshal(records)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode batch: %w", err)
+	}
+	params := map[string]string{
+		"domain_id": domainID,
+		"records":   string(data),
+	}
+
+	results, err := c.runBatch(ctx, "Batch.Record.Create", params)
+	c.invalidateRecords(domainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create records: %w", err)
+	}
+	return results, nil
+}
+
+// batchModify sets one field of several records to the same value with
+// Batch.Record.Modify and waits for the job to finish. change is the
+// field as named by the API: "value", "ttl" or "mx".
+func (c *Client) batchModify(ctx context.Context, domainID string, recordIDs []string, change, changeTo string) ([]batchResult, error) {
+	params := map[string]string{
+		"record_id": strings.Join(recordIDs, ","),
+		"change":    change,
+		"change_to": changeTo,
+	}
+
+	results, err := c.runBatch(ctx, "Batch.Record.Modify", params)
+	c.invalidateRecords(domainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update records: %w", err)
+	}
+	return results, nil
+}
+
+// runBatch starts a batch job and polls Batch.Detail until none of its
+// records are waiting any more
+func (c *Client) runBatch(ctx context.Context, action string, params map[string]string) ([]batchResult, error) {
+	body, err := c.makeRequest(ctx, action, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp batchResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse batch response: %w", err)
+	}
+
+	for {
+		results := resp.results()
+		if resp.JobID == "" || !batchPending(results) {
+			return results, nil
+		}
+
+		timer := time.NewTimer(batchPollInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("batch job %s did not finish: %w", resp.JobID, ctx.Err())
+		case <-timer.C:
+		}
+
+		body, err := c.makeRequest(ctx, "Batch.Detail", map[string]string{"job_id": resp.JobID})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get batch job %s: %w", resp.JobID, err)
+		}
+		jobID := resp.JobID
+		resp = batchResponse{}
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse batch job %s: %w", jobID, err)
+		}
+		resp.JobID = jobID
+	}
+}
+
+// batchPending reports whether some records of a job are not done yet
+func batchPending(results []batchResult) bool {
+	for _, result := range results {
+		switch strings.ToLower(result.Status) {
+		case "waiting", "running", "pending":
+			return true
+		}
+	}
+	return false
+}
+
+// batchFailed reports whether a record of a job failed
+func batchFailed(result batchResult) bool {
+	switch strings.ToLower(result.Status) {
+	case "error", "fail", "failed":
+		return true
+	}
+	return false
+}
+
+// batchable reports whether a write of rec can be part of a batch. Batch
+// jobs take line names only and cannot set weights.
+func (c *Client) batchable(rec record) bool {
+	if rec.Weight != "" {
+		return false
+	}
+	params := make(map[string]string)
+	c.lineParams(params, rec)
+	return params["record_line_id"] == ""
+}
+
+// useBatch reports whether n writes should go through the Batch.* actions
+func (p *Provider) useBatch(client *Client, n int) bool {
+	return n >= minBatchSize && client.capabilities().Batch
+}
+
+// useBatchCreate reports whether n creates should go through
+// Batch.Record.Create. With an idempotency window, each create needs its
+// own check for an earlier attempt, so they are made one by one.
+func (p *Provider) useBatchCreate(client *Client, n int) bool {
+	return p.IdempotencyWindow <= 0 && p.useBatch(client, n)
+}
+
+// batchCreateRecords creates records with Batch.Record.Create and runs the
+// post-write checks on each. Records the job reports no ID for are read
+// back with a single listing. It reports false if batching is not
+// available, after turning it off, and the records must be created one by
+// one instead. On error, the records created so far are returned, in the
+// order of recs, with nil for the others.
+func (p *Provider) batchCreateRecords(ctx context.Context, client *Client, zone, domainID string, recs []record) ([]*record, bool, error) {
+	results, err := client.batchCreate(ctx, domainID, recs)
+	if errors.Is(err, ErrNotSupported) {
+		p.degrade(client, featureBatch, err)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	stored := make([]*record, len(recs))
+	failed := make([]bool, len(recs))
+	var failures []string
+	var missing []record
+	claimed := make(map[int]bool)
+	for i, rec := range recs {
+		for j, result := range results {
+			if claimed[j] ||
+				!strings.EqualFold(result.SubDomain, rec.Name) ||
+				!strings.EqualFold(result.RecordType, rec.Type) ||
+				!sameRecordValue(rec.Type, result.Value, rec.Value) {
+				continue
+			}
+			claimed[j] = true
+
+			if batchFailed(result) {
+				failed[i] = true
+				failures = append(failures, fmt.Sprintf("%s: %s", makeAbsoluteName(rec.Name, zone), result.ErrMsg))
+			} else if result.ID != "" {
+				created := completeRecord(record{ID: result.ID}, rec)
+				stored[i] = &created
+			}
+			break
+		}
+		if stored[i] == nil && !failed[i] {
+			missing = append(missing, rec)
+		}
+	}
+
+	if len(missing) > 0 {
+		existing, err := client.lookupRRsets(ctx, domainID, missing)
+		if err != nil {
+			return nil, true, fmt.Errorf("failed to read back created records: %w", err)
+		}
+		for i, rec := range recs {
+			if stored[i] != nil || failed[i] {
+				continue
+			}
+			for _, candidate := range existing {
+				if strings.EqualFold(candidate.Name, rec.Name) &&
+					strings.EqualFold(candidate.Type, rec.Type) &&
+					sameRecordValue(rec.Type, candidate.Value, rec.Value) &&
+					client.sameLine(rec, candidate) {
+					created := completeRecord(candidate, rec)
+					stored[i] = &created
+					break
+				}
+			}
+		}
+	}
+
+	checked := make([]*record, len(recs))
+	for i, rec := range recs {
+		if stored[i] == nil {
+			continue
+		}
+		checked[i], err = p.afterWrite(ctx, client, zone, domainID, rec, stored[i])
+		if err != nil {
+			return checked, true, err
+		}
+	}
+
+	if len(failures) > 0 {
+		return checked, true, fmt.Errorf("failed to create records: %s", strings.Join(failures, "; "))
+	}
+	for i, rec := range recs {
+		if checked[i] == nil {
+			return checked, true, fmt.Errorf("failed to create record %s: not found after batch create", makeAbsoluteName(rec.Name, zone))
+		}
+	}
+	return checked, true, nil
+}
+
+// batchUpdate is a group of updates that change the same field of their
+// records to the same value
+type batchUpdate struct {
+	change   string
+	changeTo string
+}
+
+// batchUpdateKey returns the group an update can be batched in: the one
+// field it changes and its new value. It reports false if the update
+// changes several fields or none, or sets a weight. The line is not
+// changed by updates, so its ID does not matter.
+func batchUpdateKey(existing, rec record) (batchUpdate, bool) {
+	if rec.Weight != "" {
+		return batchUpdate{}, false
+	}
+
+	var changes []batchUpdate
+	if !sameRecordValue(rec.Type, existing.Value, rec.Value) {
+		changes = append(changes, batchUpdate{"value", rec.Value})
+	}
+	if rec.TTL != "" && rec.TTL != existing.TTL {
+		changes = append(changes, batchUpdate{"ttl", rec.TTL})
+	}
+	if rec.MX != "" && rec.MX != existing.MX {
+		changes = append(changes, batchUpdate{"mx", rec.MX})
+	}
+
+	if len(changes) != 1 {
+		return batchUpdate{}, false
+	}
+	return changes[0], true
+}
+
+// batchUpdateRecords applies a group of updates with Batch.Record.Modify
+// and runs the post-write checks on each. It reports false if batching is
+// not available, after turning it off.
+func (p *Provider) batchUpdateRecords(ctx context.Context, client *Client, zone, domainID string, group batchUpdate, existing, recs []record) ([]*record, bool, error) {
+	ids := make([]string, len(existing))
+	for i, rec := range existing {
+		ids[i] = rec.ID
+	}
+
+	results, err := client.batchModify(ctx, domainID, ids, group.change, group.changeTo)
+	if errors.Is(err, ErrNotSupported) {
+		p.degrade(client, featureBatch, err)
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, true, err
+	}
+
+	failed := make(map[string]string)
+	for _, result := range results {
+		if batchFailed(result) {
+			failed[result.ID] = result.ErrMsg
+		}
+	}
+
+	updated := make([]*record, len(recs))
+	for i, rec := range recs {
+		if msg, ok := failed[existing[i].ID]; ok {
+			return updated, true, fmt.Errorf("failed to update record %s: %s", makeAbsoluteName(rec.Name, zone), msg)
+		}
+
+		stored := completeRecord(record{ID: existing[i].ID, Remark: existing[i].Remark}, rec)
+		if stored.TTL == "" {
+			stored.TTL = existing[i].TTL
+		}
+		checked, err := p.afterWrite(ctx, client, zone, domainID, rec, &stored)
+		updated[i] = checked
+		if err != nil {
+			return updated, true, err
+		}
+	}
+	return updated, true, nil
+}
+
+// createRecords creates recs, those that can be batched with
+// Batch.Record.Create if there are enough of them, and calls done with the
+// index and stored record of each record created. It stops at the first
+// error.
+func (p *Provider) createRecords(ctx context.Context, client *Client, zone, domainID string, recs []record, done func(int, *record) error) error {
+	var batch []int
+	for i, rec := range recs {
+		if client.batchable(rec) {
+			batch = append(batch, i)
+		}
+	}
+
+	batched := make([]bool, len(recs))
+	if p.useBatchCreate(client, len(batch)) {
+		batchRecs := make([]record, len(batch))
+		for j, i := range batch {
+			batchRecs[j] = recs[i]
+		}
+
+		stored, ok, err := p.batchCreateRecords(ctx, client, zone, domainID, batchRecs)
+		if ok {
+			for j, i := range batch {
+				batched[i] = true
+				if j < len(stored) && stored[j] != nil {
+					if err := done(i, stored[j]); err != nil {
+						return err
+					}
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, rec := range recs {
+		if batched[i] {
+			continue
+		}
+
+		createdRec, writeErr := p.createRecord(ctx, client, zone, domainID, rec)
+		if createdRec == nil {
+			return fmt.Errorf("failed to create record %s: %w", makeAbsoluteName(rec.Name, zone), writeErr)
+		}
+		if err := done(i, createdRec); err != nil {
+			return err
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
+
+// updateRecords updates the existing records to recs, in batches of
+// updates that change the same field to the same value if there are enough
+// of them, and calls done with the index and stored record of each record
+// updated. It stops at the first error.
+func (p *Provider) updateRecords(ctx context.Context, client *Client, zone, domainID string, existing, recs []record, done func(int, *record) error) error {
+	groups := make(map[batchUpdate][]int)
+	var order []batchUpdate
+	for i := range recs {
+		group, ok := batchUpdateKey(existing[i], recs[i])
+		if !ok {
+			continue
+		}
+		if _, ok := groups[group]; !ok {
+			order = append(order, group)
+		}
+		groups[group] = append(groups[group], i)
+	}
+
+	batched := make([]bool, len(recs))
+	for _, group := range order {
+		indexes := groups[group]
+		if !p.useBatch(client, len(indexes)) {
+			continue
+		}
+
+		groupExisting := make([]record, len(indexes))
+		groupRecs := make([]record, len(indexes))
+		for j, i := range indexes {
+			groupExisting[j] = existing[i]
+			groupRecs[j] = recs[i]
+		}
+
+		stored, ok, err := p.batchUpdateRecords(ctx, client, zone, domainID, group, groupExisting, groupRecs)
+		if !ok {
+			break
+		}
+		for j, i := range indexes {
+			batched[i] = true
+			if j < len(stored) && stored[j] != nil {
+				if err := done(i, stored[j]); err != nil {
+					return err
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i, rec := range recs {
+		if batched[i] {
+			continue
+		}
+
+		updatedRec, writeErr := p.updateRecord(ctx, client, zone, domainID, existing[i], rec)
+		if updatedRec == nil {
+			return fmt.Errorf("failed to update record %s: %w", makeAbsoluteName(rec.Name, zone), writeErr)
+		}
+		if err := done(i, updatedRec); err != nil {
+			return err
+		}
+		if writeErr != nil {
+			return writeErr
+		}
+	}
+	return nil
+}
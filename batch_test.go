@@ -0,0 +1,74 @@
+package dnspod_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+func TestBatchCreate(t *testing.T) {
+	for _, batch := range []bool{true, false} {
+		name := "fallback"
+		if batch {
+			name = "batch"
+		}
+		t.Run(name, func(t *testing.T) {
+			backend := newFakeDNSPod("example.com")
+			backend.batch = batch
+			server := httptest.NewServer(backend)
+			t.Cleanup(server.Close)
+
+			var warnings []error
+			provider := &dnspod.Provider{
+				LoginToken: "1,token",
+				Endpoint:   server.URL,
+				OnWarning:  func(err error) { warnings = append(warnings, err) },
+			}
+			ctx := context.Background()
+			const zone = "example.com."
+
+			for _, text := range []string{"a", "b"} {
+				created, err := provider.AppendRecords(ctx, zone, []libdns.Record{
+					libdns.TXT{Name: "one", TTL: 10 * time.Minute, Text: text},
+					libdns.TXT{Name: "two", TTL: 10 * time.Minute, Text: text},
+					libdns.TXT{Name: "three", TTL: 10 * time.Minute, Text: text},
+				})
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, rec := range created {
+					if meta, _ := rec.(libdns.TXT).ProviderData.(dnspod.RecordMetadata); meta.ID == "" {
+						t.Errorf("created %s without an ID", rec.RR().Name)
+					}
+				}
+			}
+			if got, want := txtRecords(backend), "默认:a 默认:a 默认:a 默认:b 默认:b 默认:b"; got != want {
+				t.Errorf("zone has %s, want %s", got, want)
+			}
+
+			// A batch job per call, or a single rejected one followed by
+			// one create per record
+			wantBatch, wantSingle := 2, 0
+			if !batch {
+				wantBatch, wantSingle = 1, 6
+			}
+			if n := backend.count("Batch.Record.Create"); n != wantBatch {
+				t.Errorf("sent %d batch jobs, want %d", n, wantBatch)
+			}
+			if n := backend.count("Record.Create"); n != wantSingle {
+				t.Errorf("sent %d single creates, want %d", n, wantSingle)
+			}
+
+			_, degraded := provider.Stats().Degraded["Batch"]
+			caps, _ := provider.Capabilities(ctx)
+			if degraded == batch || caps.Batch != batch || (len(warnings) == 1) == batch {
+				t.Errorf("degraded %v, capabilities %+v, warnings %v", degraded, caps, warnings)
+			}
+		})
+	}
+}
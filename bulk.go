@@ -0,0 +1,301 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordFilter selects records for bulk operations. Empty fields match
+// every record; all non-empty fields must match.
+type RecordFilter struct {
+	// Names are record names, either absolute or relative to the zone
+	Names []string
+
+	// Types are record types such as "A" or "TXT"
+	Types []string
+
+	// Labels are labels records must carry, parsed from their remark (see
+	// ParseLabels). An empty value matches any value of the label.
+	Labels map[string]string
+
+	// RemarkPrefix selects records whose remark starts with it
+	RemarkPrefix string
+
+	// Match is an optional predicate applied after the other fields
+	Match func(libdns.Record) bool
+}
+
+// empty reports whether the filter selects every record
+func (f RecordFilter) empty() bool {
+	return len(f.Names) == 0 && len(f.Types) == 0 && len(f.Labels) == 0 && f.RemarkPrefix == "" && f.Match == nil
+}
+
+// matches reports whether a DNSPod record is selected by the filter
+func (f RecordFilter) matches(rec record, zone string) bool {
+	if len(f.Names) > 0 {
+		found := false
+		for _, name := range f.Names {
+			if strings.EqualFold(extractRecordName(makeAbsoluteName(name, zone), zone), rec.Name) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Types) > 0 {
+		found := false
+		for _, t := range f.Types {
+			if strings.EqualFold(t, rec.Type) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.Labels) > 0 {
+		labels, _ := ParseLabels(rec.Remark)
+		for key, value := range f.Labels {
+			have, ok := labels[key]
+			if !ok || (value != "" && have != value) {
+				return false
+			}
+		}
+	}
+
+	if f.RemarkPrefix != "" && !strings.HasPrefix(rec.Remark, f.RemarkPrefix) {
+		return false
+	}
+
+	if f.Match != nil && !f.Match(convertToLibDNSRecord(rec, zone)) {
+		return false
+	}
+
+	return true
+}
+
+// UpdateTTLs sets the TTL of every record in the zone selected by filter,
+// skipping records that already have it. This is typically used to lower
+// TTLs ahead of a migration and restore them afterwards. The records are
+// updated with one batch job where DNSPod supports it. It returns the
+// updated records.
+func (p *Provider) UpdateTTLs(ctx context.Context, zone string, filter RecordFilter, ttl time.Duration) ([]libdns.Record, error) {
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	client, domainID, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	newTTL := strconv.Itoa(int(ttl.Seconds()))
+
+	var existing, recs []record
+	for _, existingRec := range existingRecords {
+		if isSystemRecord(existingRec) || !filter.matches(existingRec, zone) || existingRec.TTL == newTTL {
+			continue
+		}
+
+		rec := existingRec
+		rec.TTL = newTTL
+		if !usesMX(rec.Type) {
+			rec.MX = ""
+		}
+		existing = append(existing, existingRec)
+		recs = append(recs, rec)
+	}
+
+	var updatedRecords []libdns.Record
+	err = p.updateRecords(ctx, client, zone, domainID, existing, recs, func(i int, updatedRec *record) error {
+		newLibRec := convertToLibDNSRecord(*updatedRec, zone)
+		updatedRecords = append(updatedRecords, newLibRec)
+		previous := convertToLibDNSRecord(existing[i], zone)
+		return p.journal(ctx, zone, JournalSet, []libdns.Record{previous}, []libdns.Record{newLibRec})
+	})
+	return updatedRecords, err
+}
+
+// PlanPurge plans deleting every record in the zone selected by filter,
+// such as all records labeled "managed-by=ci". The filter must not be
+// empty. The plan is not executed.
+func (p *Provider) PlanPurge(ctx context.Context, zone string, filter RecordFilter) (*Plan, error) {
+	if filter.empty() {
+		return nil, fmt.Errorf("refusing to purge every record of zone %s: the filter is empty", zone)
+	}
+
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{Zone: zone}
+	for _, existingRec := range existingRecords {
+		if isSystemRecord(existingRec) || !filter.matches(existingRec, zone) {
+			continue
+		}
+
+		existing := existingRec
+		plan.Changes = append(plan.Changes, Change{
+			Op:       ChangeDelete,
+			Before:   convertToLibDNSRecord(existingRec, zone),
+			existing: &existing,
+		})
+	}
+
+	return plan, nil
+}
+
+// Purge deletes every record selected by filter, as planned by PlanPurge
+// and approved like a Sync, and returns the applied changes
+func (p *Provider) Purge(ctx context.Context, zone string, filter RecordFilter) ([]Change, error) {
+	_, applied, err := p.approveAndApply(ctx, zone, func(ctx context.Context) (*Plan, error) {
+		return p.PlanPurge(ctx, zone, filter)
+	})
+	return applied, err
+}
+
+// PlanReplaceValue plans rewriting every record whose value is oldValue to
+// newValue, optionally restricted to the given record types. Host names are
+// compared case-insensitively and regardless of a trailing dot; for MX and
+// SRV records the target host is compared. The plan is not executed.
+func (p *Provider) PlanReplaceValue(ctx context.Context, zone, oldValue, newValue string, types ...string) (*Plan, error) {
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := RecordFilter{Types: types}
+	plan := &Plan{Zone: zone}
+
+	for _, existingRec := range existingRecords {
+		if isSystemRecord(existingRec) || !filter.matches(existingRec, zone) {
+			continue
+		}
+
+		rec, ok := replaceRecordValue(existingRec, oldValue, newValue)
+		if !ok {
+			continue
+		}
+
+		existing := existingRec
+		plan.Changes = append(plan.Changes, Change{
+			Op:       ChangeUpdate,
+			Before:   convertToLibDNSRecord(existingRec, zone),
+			After:    convertToLibDNSRecord(rec, zone),
+			existing: &existing,
+		})
+	}
+
+	return plan, nil
+}
+
+// ReplaceValue rewrites every record whose value is oldValue to newValue,
+// as planned by PlanReplaceValue and approved like a Sync, and returns the
+// applied changes. This is the usual operation when moving services to a
+// new server.
+func (p *Provider) ReplaceValue(ctx context.Context, zone, oldValue, newValue string, types ...string) ([]Change, error) {
+	_, applied, err := p.approveAndApply(ctx, zone, func(ctx context.Context) (*Plan, error) {
+		return p.PlanReplaceValue(ctx, zone, oldValue, newValue, types...)
+	})
+	return applied, err
+}
+
+// replaceRecordValue returns rec with oldValue replaced by newValue, or
+// false if the record does not point at oldValue
+func replaceRecordValue(rec record, oldValue, newValue string) (record, bool) {
+	if !pointsAt(rec, oldValue) {
+		return rec, false
+	}
+
+	if strings.EqualFold(rec.Type, "SRV") {
+		fields := strings.Fields(rec.Value)
+		fields[len(fields)-1] = newValue
+		rec.Value = strings.Join(fields, " ")
+		return rec, true
+	}
+
+	rec.Value = newValue
+	return rec, true
+}
+
+// pointsAt reports whether a record's value (or SRV target) is value
+func pointsAt(rec record, value string) bool {
+	if strings.EqualFold(rec.Type, "SRV") {
+		fields := strings.Fields(rec.Value)
+		return len(fields) > 0 && sameValue(fields[len(fields)-1], value)
+	}
+	return sameValue(rec.Value, value)
+}
+
+// sameValue compares record values, ignoring case and a trailing dot
+func sameValue(a, b string) bool {
+	return strings.EqualFold(strings.TrimSuffix(a, "."), strings.TrimSuffix(b, "."))
+}
+
+// sameRecordValue compares the values of two records of the given type.
+// Text values are compared exactly, SRV, CAA, HTTPS and SVCB values field
+// by field, and everything else as by sameValue.
+func sameRecordValue(typ, a, b string) bool {
+	switch strings.ToUpper(typ) {
+	case "TXT", "SPF":
+		return a == b
+	case "SRV":
+		// Either value may leave out the priority, which DNSPod keeps in
+		// the mx field
+		srvA, errA := ParseSRVValue(a)
+		srvB, errB := ParseSRVValue(b)
+		if errA != nil || errB != nil {
+			break
+		}
+		if len(strings.Fields(a)) != len(strings.Fields(b)) {
+			srvA.Priority, srvB.Priority = 0, 0
+		}
+		return srvA.Priority == srvB.Priority && srvA.Weight == srvB.Weight &&
+			srvA.Port == srvB.Port && sameValue(srvA.Target, srvB.Target)
+	case "CAA":
+		caaA, errA := ParseCAAValue(a)
+		caaB, errB := ParseCAAValue(b)
+		if errA != nil || errB != nil {
+			break
+		}
+		return caaA == caaB
+	case "HTTPS", "SVCB":
+		svcA, okA := canonicalServiceBinding(a)
+		svcB, okB := canonicalServiceBinding(b)
+		if !okA || !okB {
+			break
+		}
+		return sameValue(svcA, svcB)
+	}
+	return sameValue(a, b)
+}
+
+// listZoneRecords resolves a zone and lists all of its records
+func (p *Provider) listZoneRecords(ctx context.Context, zone string) (*Client, string, []record, error) {
+	client := p.getClient()
+
+	domainID, err := client.getDomainID(ctx, zone)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
+	}
+
+	records, err := client.listRecords(ctx, domainID)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("failed to list records for zone %s: %w", zone, err)
+	}
+
+	return client, domainID, records, nil
+}
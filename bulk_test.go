@@ -0,0 +1,115 @@
+package dnspod_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+func TestUpdateTTLs(t *testing.T) {
+	for _, batch := range []bool{true, false} {
+		name := "fallback"
+		if batch {
+			name = "batch"
+		}
+		t.Run(name, func(t *testing.T) {
+			backend := newFakeDNSPod("example.com")
+			backend.batch = batch
+			ids := []string{
+				backend.add("www", "A", "192.0.2.1", "600"),
+				backend.add("api", "A", "192.0.2.2", "600"),
+				backend.add("mail", "MX", "mx.example.com.", "600"),
+			}
+			unchanged := backend.add("low", "A", "192.0.2.3", "300")
+			server := httptest.NewServer(backend)
+			t.Cleanup(server.Close)
+
+			provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL}
+			updated, err := provider.UpdateTTLs(context.Background(), "example.com.", dnspod.RecordFilter{}, 5*time.Minute)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(updated) != len(ids) {
+				t.Errorf("updated %d records, want %d", len(updated), len(ids))
+			}
+			for _, rec := range updated {
+				if ttl := rec.RR().TTL; ttl != 5*time.Minute {
+					t.Errorf("%s returned with TTL %v", rec.RR().Name, ttl)
+				}
+			}
+			for _, id := range append(ids, unchanged) {
+				if ttl := backend.field(id, "ttl"); ttl != "300" {
+					t.Errorf("record %s has TTL %s, want 300", id, ttl)
+				}
+			}
+
+			wantBatch, wantSingle := 1, 0
+			if !batch {
+				wantSingle = len(ids)
+			}
+			if n := backend.count("Batch.Record.Modify"); n != wantBatch {
+				t.Errorf("sent %d batch jobs, want %d", n, wantBatch)
+			}
+			if n := backend.count("Record.Modify"); n != wantSingle {
+				t.Errorf("sent %d single updates, want %d", n, wantSingle)
+			}
+		})
+	}
+}
+
+func TestReplaceValue(t *testing.T) {
+	for _, batch := range []bool{true, false} {
+		name := "fallback"
+		if batch {
+			name = "batch"
+		}
+		t.Run(name, func(t *testing.T) {
+			backend := newFakeDNSPod("example.com")
+			backend.batch = batch
+			ids := []string{
+				backend.add("www", "A", "192.0.2.1", "600"),
+				backend.add("api", "A", "192.0.2.1", "600"),
+				backend.add("cdn", "A", "192.0.2.1", "600"),
+			}
+			other := backend.add("db", "A", "192.0.2.2", "600")
+			server := httptest.NewServer(backend)
+			t.Cleanup(server.Close)
+
+			provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL}
+			changes, err := provider.ReplaceValue(context.Background(), "example.com.", "192.0.2.1", "192.0.2.9", "A")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(changes) != len(ids) {
+				t.Fatalf("applied %d changes, want %d", len(changes), len(ids))
+			}
+			for i, change := range changes {
+				if change.Op != dnspod.ChangeUpdate || change.After.RR().Data != "192.0.2.9" {
+					t.Errorf("change %d: %+v", i, change)
+				}
+			}
+			for _, id := range ids {
+				if value := backend.field(id, "value"); value != "192.0.2.9" {
+					t.Errorf("record %s has value %s", id, value)
+				}
+			}
+			if value := backend.field(other, "value"); value != "192.0.2.2" {
+				t.Errorf("unrelated record changed to %s", value)
+			}
+
+			wantSingle := 0
+			if !batch {
+				wantSingle = len(ids)
+			}
+			if n := backend.count("Batch.Record.Modify"); n != 1 {
+				t.Errorf("sent %d batch jobs, want 1", n)
+			}
+			if n := backend.count("Record.Modify"); n != wantSingle {
+				t.Errorf("sent %d single updates, want %d", n, wantSingle)
+			}
+		})
+	}
+}
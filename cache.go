@@ -0,0 +1,158 @@
+package dnspod
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// recordCache caches Record.List results per domain ID. Entries expire
+// after ttl and are dropped whenever the domain is written to through the
+// same client.
+type recordCache struct {
+	mutex   sync.Mutex
+	ttl     time.Duration
+	entries map[string]*recordCacheEntry
+
+	// hits, misses and evictions count lookups and dropped entries
+	hits      int64
+	misses    int64
+	evictions int64
+}
+
+type recordCacheEntry struct {
+	records []record
+	fetched time.Time
+
+	// byValue indexes records by normalized value (the target for SRV
+	// records). It is built on first use.
+	byValue map[string][]int
+}
+
+// newRecordCache creates a cache, or returns nil if ttl disables caching
+func newRecordCache(ttl time.Duration) *recordCache {
+	if ttl <= 0 {
+		return nil
+	}
+	return &recordCache{
+		ttl:     ttl,
+		entries: make(map[string]*recordCacheEntry),
+	}
+}
+
+// get returns a copy of the cached records for a domain, if fresh
+func (rc *recordCache) get(domainID string) ([]record, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	entry, ok := rc.lookup(domainID)
+	if !ok {
+		rc.misses++
+		return nil, false
+	}
+	rc.hits++
+
+	records := make([]record, len(entry.records))
+	copy(records, entry.records)
+	return records, true
+}
+
+// put stores the records of a domain
+func (rc *recordCache) put(domainID string, records []record) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	stored := make([]record, len(records))
+	copy(stored, records)
+	rc.entries[domainID] = &recordCacheEntry{records: stored, fetched: time.Now()}
+}
+
+// invalidate drops the cached records of a domain
+func (rc *recordCache) invalidate(domainID string) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	if _, ok := rc.entries[domainID]; ok {
+		delete(rc.entries, domainID)
+		rc.evictions++
+	}
+}
+
+// pointingAt returns the cached records of a domain whose value (or SRV
+// target) is value, using the reverse index. It reports false if the
+// domain is not cached or expired; misses are left for get to count.
+func (rc *recordCache) pointingAt(domainID, value string) ([]record, bool) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	entry, ok := rc.lookup(domainID)
+	if !ok {
+		return nil, false
+	}
+	rc.hits++
+
+	if entry.byValue == nil {
+		entry.byValue = make(map[string][]int)
+		for i, rec := range entry.records {
+			for _, key := range indexKeys(rec) {
+				entry.byValue[key] = append(entry.byValue[key], i)
+			}
+		}
+	}
+
+	var records []record
+	for _, i := range entry.byValue[indexKey(value)] {
+		records = append(records, entry.records[i])
+	}
+	return records, true
+}
+
+// lookup returns a fresh entry, evicting it if expired. The caller must
+// hold the mutex.
+func (rc *recordCache) lookup(domainID string) (*recordCacheEntry, bool) {
+	entry, ok := rc.entries[domainID]
+	if !ok {
+		return nil, false
+	}
+	if time.Since(entry.fetched) > rc.ttl {
+		delete(rc.entries, domainID)
+		rc.evictions++
+		return nil, false
+	}
+	return entry, true
+}
+
+// stats returns the cache statistics
+func (rc *recordCache) stats() CacheStats {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	stats := CacheStats{
+		Hits:      rc.hits,
+		Misses:    rc.misses,
+		Evictions: rc.evictions,
+		Entries:   len(rc.entries),
+	}
+	for _, entry := range rc.entries {
+		if age := time.Since(entry.fetched); age > stats.Age {
+			stats.Age = age
+		}
+	}
+	return stats
+}
+
+// indexKeys returns the reverse index keys for a record
+func indexKeys(rec record) []string {
+	if strings.EqualFold(rec.Type, "SRV") {
+		if fields := strings.Fields(rec.Value); len(fields) > 0 {
+			return []string{indexKey(fields[len(fields)-1])}
+		}
+		return nil
+	}
+	return []string{indexKey(rec.Value)}
+}
+
+// indexKey normalizes a value the same way sameValue compares them
+func indexKey(value string) string {
+	return strings.ToLower(strings.TrimSuffix(value, "."))
+}
@@ -0,0 +1,53 @@
+package dnspod
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRecordsPointingAtUsesIndex(t *testing.T) {
+	var lists atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lists.Add(1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"status":{"code":"1","message":"ok"},"info":{"record_total":"3"},"records":[
+			{"id":"1","name":"www","type":"CNAME","value":"target.example.net.","ttl":"600","line":"默认","enabled":"1"},
+			{"id":"2","name":"_sip._tcp","type":"SRV","value":"10 5060 Target.example.net.","mx":"1","ttl":"600","line":"默认","enabled":"1"},
+			{"id":"3","name":"api","type":"A","value":"192.0.2.1","ttl":"600","line":"默认","enabled":"1"}]}`))
+	}))
+	t.Cleanup(server.Close)
+
+	c := newClient("1,token", server.URL)
+	c.records = newRecordCache(time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		records, err := c.recordsPointingAt(ctx, "1", "target.example.net")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(records) != 2 || records[0].ID != "1" || records[1].ID != "2" {
+			t.Errorf("lookup %d returned %+v, want records 1 and 2", i, records)
+		}
+	}
+	if n := lists.Load(); n != 1 {
+		t.Errorf("listed the records %d times, want once", n)
+	}
+	if stats := c.records.stats(); stats.Misses != 1 || stats.Hits != 2 {
+		t.Errorf("cache stats %+v, want 1 miss and 2 hits", stats)
+	}
+
+	// Without the cache, the records are listed every time
+	c.records = nil
+	records, err := c.recordsPointingAt(ctx, "1", "192.0.2.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || records[0].ID != "3" || lists.Load() != 2 {
+		t.Errorf("uncached lookup returned %+v after %d listings", records, lists.Load())
+	}
+}
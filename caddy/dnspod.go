@@ -0,0 +1,379 @@
+// Package dnspod registers the DNSPod libdns provider as a Caddy DNS
+// provider module (dns.providers.dnspod_global).
+package dnspod
+
+import (
+	"strconv"
+
+	"github.com/caddyserver/caddy/v2"
+	"github.com/caddyserver/caddy/v2/caddyconfig/caddyfile"
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+// Provider wraps the DNSPod provider as a Caddy module
+type Provider struct{ *dnspod.Provider }
+
+func init() {
+	caddy.RegisterModule(Provider{})
+}
+
+// CaddyModule returns the Caddy module information
+func (Provider) CaddyModule() caddy.ModuleInfo {
+	return caddy.ModuleInfo{
+		ID:  "dns.providers.dnspod_global",
+		New: func() caddy.Module { return &Provider{new(dnspod.Provider)} },
+	}
+}
+
+// Provision resolves {env.*} placeholders and sets up the API client. The
+// placeholders themselves are kept, so the config marshals back out
+// without exposing the token.
+func (p *Provider) Provision(ctx caddy.Context) error {
+	return p.Provider.Provision()
+}
+
+// UnmarshalCaddyfile sets up the DNS provider from Caddyfile tokens. Syntax:
+//
+//	dnspod_global [<login_token>] {
+//	    login_token <login_token>
+//	    secret_id <secret_id>
+//	    secret_key <secret_key>
+//	    endpoint <url>
+//	    mirrors <urls...>
+//	    language cn|en
+//	    default_line <line>
+//	    line_alias <alias> <line>
+//	    line_id <line> <id>
+//	    hedge_after <duration>
+//	    rate_limit <action|*> <requests_per_second>
+//	    adaptive_throttle
+//	    max_retries <n>
+//	    retry_backoff <duration>
+//	    include_system_records
+//	    strict
+//	    skip_inactive_zones
+//	    domain_cache_ttl <duration>
+//	    record_cache_ttl <duration>
+//	    ttl_coercion ignore|warn|error
+//	    verify
+//	    serialize_zone_writes
+//	    single_writer
+//	    coalesce_window <duration>
+//	    idempotency_window <duration>
+//	    webhooks <urls...>
+//	    propagation_timeout <duration>
+//	    poll_interval <duration>
+//	    resolvers <addresses...>
+//	    check_authoritative
+//	    propagation_quorum <n>
+//	}
+func (p *Provider) UnmarshalCaddyfile(d *caddyfile.Dispenser) error {
+	d.Next() // consume directive name
+
+	if d.NextArg() {
+		p.Provider.LoginToken = d.Val()
+	}
+	if d.NextArg() {
+		return d.ArgErr()
+	}
+
+	for nesting := d.Nesting(); d.NextBlock(nesting); {
+		switch d.Val() {
+		case "login_token":
+			if p.Provider.LoginToken != "" {
+				return d.Err("login token already set")
+			}
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.LoginToken = d.Val()
+
+		case "secret_id":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.SecretID = d.Val()
+
+		case "secret_key":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.SecretKey = d.Val()
+
+		case "endpoint":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.Endpoint = d.Val()
+
+		case "mirrors":
+			p.Provider.Mirrors = append(p.Provider.Mirrors, d.RemainingArgs()...)
+			if len(p.Provider.Mirrors) == 0 {
+				return d.ArgErr()
+			}
+
+		case "language":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.Language = d.Val()
+
+		case "default_line":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			p.Provider.DefaultLine = d.Val()
+
+		case "line_alias":
+			args := d.RemainingArgs()
+			if len(args) != 2 {
+				return d.ArgErr()
+			}
+			if p.Provider.LineAliases == nil {
+				p.Provider.LineAliases = make(map[string]string)
+			}
+			p.Provider.LineAliases[args[0]] = args[1]
+
+		case "line_id":
+			args := d.RemainingArgs()
+			if len(args) != 2 {
+				return d.ArgErr()
+			}
+			if p.Provider.LineIDs == nil {
+				p.Provider.LineIDs = make(map[string]string)
+			}
+			p.Provider.LineIDs[args[0]] = args[1]
+
+		case "hedge_after":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			delay, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid hedge_after: %v", err)
+			}
+			p.Provider.HedgeAfter = delay
+
+		case "rate_limit":
+			args := d.RemainingArgs()
+			if len(args) != 2 {
+				return d.ArgErr()
+			}
+			rate, err := strconv.ParseFloat(args[1], 64)
+			if err != nil || rate <= 0 {
+				return d.Errf("invalid rate limit %q", args[1])
+			}
+			if p.Provider.RateLimits == nil {
+				p.Provider.RateLimits = make(map[string]float64)
+			}
+			p.Provider.RateLimits[args[0]] = rate
+
+		case "adaptive_throttle":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.AdaptiveThrottle = enabled
+
+		case "max_retries":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			retries, err := strconv.Atoi(d.Val())
+			if err != nil {
+				return d.Errf("invalid max_retries %q", d.Val())
+			}
+			p.Provider.MaxRetries = retries
+
+		case "retry_backoff":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			backoff, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid retry_backoff: %v", err)
+			}
+			p.Provider.RetryBackoff = backoff
+
+		case "include_system_records":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.IncludeSystemRecords = enabled
+
+		case "strict":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.Strict = enabled
+
+		case "skip_inactive_zones":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.SkipInactiveZones = enabled
+
+		case "domain_cache_ttl":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			ttl, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid domain_cache_ttl: %v", err)
+			}
+			p.Provider.DomainCacheTTL = ttl
+
+		case "record_cache_ttl":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			ttl, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid record_cache_ttl: %v", err)
+			}
+			p.Provider.RecordCacheTTL = ttl
+
+		case "ttl_coercion":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			switch d.Val() {
+			case "ignore":
+				p.Provider.TTLCoercion = dnspod.TTLPolicyIgnore
+			case "warn":
+				p.Provider.TTLCoercion = dnspod.TTLPolicyWarn
+			case "error":
+				p.Provider.TTLCoercion = dnspod.TTLPolicyError
+			default:
+				return d.Errf("invalid ttl_coercion %q: expected ignore, warn or error", d.Val())
+			}
+
+		case "verify":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.Verify = enabled
+
+		case "serialize_zone_writes":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.SerializeZoneWrites = enabled
+
+		case "single_writer":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.SingleWriter = enabled
+
+		case "coalesce_window":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			window, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid coalesce_window: %v", err)
+			}
+			p.Provider.CoalesceWindow = window
+
+		case "idempotency_window":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			window, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid idempotency_window: %v", err)
+			}
+			p.Provider.IdempotencyWindow = window
+
+		case "webhooks":
+			p.Provider.Webhooks = append(p.Provider.Webhooks, d.RemainingArgs()...)
+			if len(p.Provider.Webhooks) == 0 {
+				return d.ArgErr()
+			}
+
+		case "propagation_timeout":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			timeout, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid propagation_timeout: %v", err)
+			}
+			p.Provider.PropagationTimeout = timeout
+
+		case "poll_interval":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			interval, err := caddy.ParseDuration(d.Val())
+			if err != nil {
+				return d.Errf("invalid poll_interval: %v", err)
+			}
+			p.Provider.PollInterval = interval
+
+		case "resolvers":
+			p.Provider.Resolvers = append(p.Provider.Resolvers, d.RemainingArgs()...)
+			if len(p.Provider.Resolvers) == 0 {
+				return d.ArgErr()
+			}
+
+		case "check_authoritative":
+			enabled, err := parseFlag(d)
+			if err != nil {
+				return err
+			}
+			p.Provider.CheckAuthoritative = enabled
+
+		case "propagation_quorum":
+			if !d.NextArg() {
+				return d.ArgErr()
+			}
+			quorum, err := strconv.Atoi(d.Val())
+			if err != nil || quorum < 0 {
+				return d.Errf("invalid propagation_quorum %q", d.Val())
+			}
+			p.Provider.PropagationQuorum = quorum
+
+		default:
+			return d.Errf("unrecognized subdirective '%s'", d.Val())
+		}
+
+		if d.NextArg() {
+			return d.ArgErr()
+		}
+	}
+
+	if p.Provider.LoginToken == "" && p.Provider.SecretID == "" {
+		return d.Err("missing login token or secret_id")
+	}
+	if (p.Provider.SecretID == "") != (p.Provider.SecretKey == "") {
+		return d.Err("secret_id and secret_key must be set together")
+	}
+
+	return nil
+}
+
+// parseFlag reads an optional boolean argument; a bare flag means true
+func parseFlag(d *caddyfile.Dispenser) (bool, error) {
+	if !d.NextArg() {
+		return true, nil
+	}
+	enabled, err := strconv.ParseBool(d.Val())
+	if err != nil {
+		return false, d.Errf("invalid boolean %q: %v", d.Val(), err)
+	}
+	return enabled, nil
+}
+
+// Interface guards
+var (
+	_ caddyfile.Unmarshaler = (*Provider)(nil)
+	_ caddy.Provisioner     = (*Provider)(nil)
+)
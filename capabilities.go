@@ -0,0 +1,161 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Optional features that are turned off when the API reports them as not
+// supported
+const (
+	featureDdns   = "Record.Ddns"
+	featureRemark = "Record.Remark"
+	featureBatch  = "Batch"
+	featureWeight = "Weight"
+)
+
+// Capabilities describes the features offered by the API endpoint a client
+// talks to. The flags start out as what the endpoint is known to offer.
+// Capabilities probes Batch; every flag is cleared once the API reports the
+// feature as not supported, as listed in Stats().Degraded.
+type Capabilities struct {
+	// Version is the API version reported by Info.Version
+	Version string
+
+	// International is set for the international API (api.dnspod.com)
+	International bool
+
+	// Batch reports whether the Batch.* actions are available. Only
+	// dnsapi.cn offers them.
+	Batch bool
+
+	// Ddns reports whether Record.Ddns is available for address updates;
+	// without it, Record.Modify is used
+	Ddns bool
+
+	// Remarks reports whether record remarks, and with them labels, can
+	// be written
+	Remarks bool
+
+	// Weights reports whether record weights can be written. DNSPod only
+	// offers them on some plans.
+	Weights bool
+}
+
+// defaultCapabilities returns the capabilities an endpoint is known to have
+// before anything was detected
+func defaultCapabilities(endpoint string) Capabilities {
+	international := strings.TrimSuffix(endpoint, "/") == intlBaseURL
+	return Capabilities{
+		International: international,
+		Batch:         !international,
+		Ddns:          true,
+		Remarks:       true,
+		Weights:       true,
+	}
+}
+
+// Capabilities detects the API version with Info.Version on first use and
+// returns the capabilities of the endpoint. If batch jobs are expected, a
+// Batch.Detail lookup of a job that does not exist checks that the action
+// is there. A failed detection is retried on the next call.
+func (c *Client) Capabilities(ctx context.Context) (Capabilities, error) {
+	c.capsMutex.Lock()
+	defer c.capsMutex.Unlock()
+
+	if c.caps.Version != "" {
+		return c.caps, nil
+	}
+
+	body, err := c.makeRequest(ctx, "Info.Version", nil)
+	if err != nil {
+		return c.caps, fmt.Errorf("failed to detect API capabilities: %w", err)
+	}
+
+	// The version is the message of the status
+	var resp apiResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return c.caps, fmt.Errorf("failed to parse version response: %w", err)
+	}
+	c.caps.Version = resp.Status.Message
+
+	// An unknown job is an API error; only a missing action turns batching
+	// off, anything else leaves it to the first batch write
+	if c.caps.Batch {
+		_, err := c.makeRequest(ctx, "Batch.Detail", map[string]string{"job_id": "0"})
+		if errors.Is(err, ErrNotSupported) {
+			c.degradeLocked(featureBatch, err)
+		}
+	}
+
+	return c.caps, nil
+}
+
+// capabilities returns the capabilities known so far, without detecting
+func (c *Client) capabilities() Capabilities {
+	c.capsMutex.Lock()
+	defer c.capsMutex.Unlock()
+
+	return c.caps
+}
+
+// Capabilities returns the capabilities of the configured API endpoint,
+// detecting them on first use
+func (p *Provider) Capabilities(ctx context.Context) (Capabilities, error) {
+	return p.getClient().Capabilities(ctx)
+}
+
+// isNotSupportedMessage reports whether an API error message says that an
+// action or feature is not available
+func isNotSupportedMessage(message string) bool {
+	message = strings.ToLower(message)
+	for _, phrase := range []string{"not support", "unsupported", "不支持"} {
+		if strings.Contains(message, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// degrade turns off an optional feature after the API reported it as not
+// supported. It reports false if the feature was already off.
+func (c *Client) degrade(feature string, err error) bool {
+	c.capsMutex.Lock()
+	defer c.capsMutex.Unlock()
+
+	return c.degradeLocked(feature, err)
+}
+
+// degradeLocked is degrade with capsMutex held
+func (c *Client) degradeLocked(feature string, err error) bool {
+	if _, ok := c.degraded[feature]; ok {
+		return false
+	}
+	if c.degraded == nil {
+		c.degraded = make(map[string]string)
+	}
+	c.degraded[feature] = err.Error()
+
+	switch feature {
+	case featureDdns:
+		c.caps.Ddns = false
+	case featureRemark:
+		c.caps.Remarks = false
+	case featureBatch:
+		c.caps.Batch = false
+	case featureWeight:
+		c.caps.Weights = false
+	}
+	return true
+}
+
+// degrade turns off an optional feature and warns about it once; the
+// caller falls back to the basic per-record path
+func (p *Provider) degrade(client *Client, feature string, err error) {
+	if client.degrade(feature, err) {
+		p.warn(fmt.Errorf("%s is unavailable, falling back: %w", feature, err))
+	}
+}
@@ -0,0 +1,72 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	tests := []struct {
+		name      string
+		batch     bool
+		wantBatch bool
+	}{
+		{"batch available", true, true},
+		{"batch missing", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				status := map[string]string{"code": "1", "message": "4.6"}
+				switch strings.TrimPrefix(r.URL.Path, "/") {
+				case "Info.Version":
+				case "Batch.Detail":
+					if !tt.batch {
+						http.NotFound(w, r)
+						return
+					}
+					status = map[string]string{"code": "-1", "message": "Job not found"}
+				default:
+					t.Errorf("unexpected request %s", r.URL.Path)
+				}
+				json.NewEncoder(w).Encode(map[string]any{"status": status})
+			}))
+			t.Cleanup(server.Close)
+
+			p := &Provider{LoginToken: "1,token", Endpoint: server.URL, MaxRetries: -1}
+			caps, err := p.Capabilities(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if caps.Version != "4.6" {
+				t.Errorf("version %q, want 4.6", caps.Version)
+			}
+			if caps.Batch != tt.wantBatch {
+				t.Errorf("batch %v, want %v", caps.Batch, tt.wantBatch)
+			}
+			if _, degraded := p.Stats().Degraded[featureBatch]; degraded == tt.wantBatch {
+				t.Errorf("degraded features %v", p.Stats().Degraded)
+			}
+		})
+	}
+}
+
+func TestCapabilitiesV3(t *testing.T) {
+	server := newV3Server(t, func(action string, payload map[string]any) any {
+		t.Errorf("unexpected action %s", action)
+		return map[string]any{}
+	})
+
+	p := &Provider{SecretID: "id", SecretKey: "key", Endpoint: server.URL}
+	caps, err := p.Capabilities(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(caps.Version, "3.0") || caps.Batch {
+		t.Errorf("got %+v, want version 3.0 without batch jobs", caps)
+	}
+}
@@ -1,16 +1,24 @@
 package dnspod
 
 import (
+	"bytes"
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"net/http"
 	"net/netip"
 	"net/url"
+	"path"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/libdns/libdns"
@@ -20,12 +28,35 @@ const (
 	// DNSPod API base URL - must use HTTPS as per API requirements
 	baseURL = "https://dnsapi.cn"
 
+	// International DNSPod API base URL, used by dnspod.com accounts
+	intlBaseURL = "https://api.dnspod.com"
+
 	// Common response codes
 	successCode = "1"
 
 	// UserAgent format as required by DNSPod API: Program Name/Version (Contact Email)
 	// DNSPod requires this exact format, otherwise the account will be banned
 	userAgent = "libdns-dnspod/1.0.0 (github.com/r6c/dnspodGlobal)"
+
+	// recordPageSize is the number of records requested per Record.List
+	// call, the most DNSPod returns
+	recordPageSize = 3000
+
+	// domainPageSize is the number of domains requested per Domain.List
+	// call
+	domainPageSize = 3000
+
+	// defaultDomainCacheTTL is how long the domain list is cached by
+	// default
+	defaultDomainCacheTTL = 10 * time.Minute
+
+	// domainRefreshInterval is the least time between fetches of the
+	// domain list caused by lookups of unknown domains
+	domainRefreshInterval = 30 * time.Second
+
+	// maxRRsetLookups is the number of RRsets a write looks up one by one
+	// before listing the whole zone instead
+	maxRRsetLookups = 10
 )
 
 // DNSPod API response structures
@@ -48,7 +79,8 @@ type domainListResponse struct {
 type recordListResponse struct {
 	apiResponse
 	Info struct {
-		SubDomains string `json:"sub_domains"`
+		SubDomains  string      `json:"sub_domains"`
+		RecordTotal json.Number `json:"record_total"`
 	} `json:"info"`
 	Records []record `json:"records"`
 }
@@ -58,10 +90,49 @@ type recordResponse struct {
 	Record record `json:"record"`
 }
 
+type recordInfoResponse struct {
+	apiResponse
+	Record recordInfo `json:"record"`
+}
+
+type domainInfoResponse struct {
+	apiResponse
+	Domain domainInfo `json:"domain"`
+}
+
 type domain struct {
-	ID     json.Number `json:"id"`
-	Name   string      `json:"name"`
-	Status string      `json:"status"`
+	ID      json.Number `json:"id"`
+	Name    string      `json:"name"`
+	Status  string      `json:"status"`
+	Grade   string      `json:"grade"`
+	GroupID string      `json:"group_id"`
+	Records string      `json:"records"`
+}
+
+type domainInfo struct {
+	ID        json.Number `json:"id"`
+	Name      string      `json:"name"`
+	Status    string      `json:"status"`
+	Grade     string      `json:"grade"`
+	DNSPodNS  []string    `json:"dnspod_ns"`
+	UpdatedOn string      `json:"updated_on"`
+}
+
+// recordInfo is a record as returned by Record.Info, which names some
+// fields differently from Record.List
+type recordInfo struct {
+	ID           string          `json:"id"`
+	SubDomain    string          `json:"sub_domain"`
+	RecordType   string          `json:"record_type"`
+	RecordLine   string          `json:"record_line"`
+	RecordLineID string          `json:"record_line_id"`
+	Value        string          `json:"value"`
+	Weight       json.RawMessage `json:"weight"`
+	MX           string          `json:"mx"`
+	TTL          string          `json:"ttl"`
+	Enabled      string          `json:"enabled"`
+	Remark       string          `json:"remark"`
+	UpdatedOn    string          `json:"updated_on"`
 }
 
 type record struct {
@@ -83,23 +154,115 @@ type record struct {
 // Client wraps HTTP client for DNSPod API
 type Client struct {
 	httpClient *http.Client
+	baseURL    string
 	loginToken string
 	mutex      sync.RWMutex
 	domainList []domain
+
+	// domainsFetched is when domainList was fetched, and domainCacheTTL
+	// how long it is used for; domainHits and domainMisses count domain
+	// lookups served from and missing the list
+	domainsFetched time.Time
+	domainCacheTTL time.Duration
+	domainHits     atomic.Int64
+	domainMisses   atomic.Int64
+
+	// skipInactiveZones makes paused and locked domains resolve as not found
+	skipInactiveZones bool
+
+	// records caches record listings; nil when caching is disabled
+	records *recordCache
+
+	// endpoints are the base URL followed by its mirrors; active is the
+	// index of the last one that was reachable
+	endpoints []apiEndpoint
+	active    atomic.Int32
+
+	// lang is the language of API messages, "cn" or "en"
+	lang string
+
+	// lineAliases map user line names to DNSPod line names, and lineIDs
+	// DNSPod line names to line IDs
+	lineAliases map[string]string
+	lineIDs     map[string]string
+
+	// defaultLine is used for records without a line
+	defaultLine string
+
+	// hedgeAfter is the delay before hedging a read; zero disables it
+	hedgeAfter time.Duration
+
+	// limiters rate limit requests per API action; nil means unlimited
+	limiters map[string]*rateLimiter
+
+	// adaptive lowers limiter rates after frequency-limit errors
+	adaptive bool
+
+	// requests and rateLimited count API responses for Stats, and retries
+	// the requests sent again after a failure
+	requests    atomic.Int64
+	rateLimited atomic.Int64
+	retries     atomic.Int64
+
+	// maxRetries is how often a failed request is retried, with delays
+	// starting at retryBackoff and doubling each time
+	maxRetries   int
+	retryBackoff time.Duration
+
+	// writes serializes mutations per zone
+	writes zoneQueue
+
+	// rrsets serializes SetRecords calls per RRset
+	rrsets rrsetLocks
+
+	// caps are the endpoint's capabilities; the version is filled in by
+	// Capabilities. degraded maps the features found unavailable to the
+	// error that showed it.
+	capsMutex sync.Mutex
+	caps      Capabilities
+	degraded  map[string]string
+
+	// pendingSets buffers SetRecords calls within the coalescing window
+	pendingSets coalescer
+
+	// configErr is why the client could not be set up from the provider
+	// configuration; if set, every request fails with it
+	configErr error
 }
 
 // newClient creates a new DNSPod API client
-func newClient(loginToken string) *Client {
-	return &Client{
+func newClient(loginToken, endpoint string) *Client {
+	if endpoint == "" {
+		endpoint = baseURL
+	}
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 		},
-		loginToken: loginToken,
+		baseURL:     strings.TrimSuffix(endpoint, "/"),
+		loginToken:  loginToken,
+		lang:        "cn",
+		defaultLine: defaultLineCN,
+
+		domainCacheTTL: defaultDomainCacheTTL,
+		maxRetries:     defaultMaxRetries,
+		retryBackoff:   defaultRetryBackoff,
+	}
+	if c.baseURL == intlBaseURL {
+		c.lang = "en"
+		c.defaultLine = defaultLineIntl
 	}
+	c.endpoints = []apiEndpoint{{url: c.baseURL, httpClient: c.httpClient}}
+	c.caps = defaultCapabilities(c.baseURL)
+	return c
 }
 
-// makeRequest makes an HTTP POST request to DNSPod API
+// makeRequest makes an HTTP POST request to DNSPod API. Failures that are
+// safe to repeat are retried with exponential backoff; see retryable.
 func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[string]string) ([]byte, error) {
+	if c.configErr != nil {
+		return nil, c.configErr
+	}
 	if params == nil {
 		params = make(map[string]string)
 	}
@@ -108,42 +271,49 @@ func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[st
 	// See: https://docs.dnspod.com/api/common-request-parameters/
 	params["login_token"] = c.loginToken
 	params["format"] = "json"       // Recommended format
-	params["error_on_empty"] = "no" // Don't return error when no results
-	params["lang"] = "cn"           // Use Chinese for better error messages (CN API specific)
+	params["error_on_empty"] = "no" // Empty lists are not errors; see Provider.ErrorOnEmpty
+	params["lang"] = c.lang         // Language of API messages, see Provider.Language
 
 	// Prepare form data
 	data := url.Values{}
 	for key, value := range params {
 		data.Set(key, value)
 	}
+	form := data.Encode()
 
-	// Create request
-	reqURL := fmt.Sprintf("%s/%s", baseURL, endpoint)
-	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, strings.NewReader(data.Encode()))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	// Set required headers as per DNSPod API specification
-	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
-	req.Header.Set("User-Agent", userAgent)
+	for attempt := 0; ; attempt++ {
+		body, err := c.sendRequest(ctx, endpoint, form)
+		if err == nil || attempt >= c.maxRetries || ctx.Err() != nil || !retryable(endpoint, err) {
+			return body, err
+		}
 
-	// Make request
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to make request: %w", err)
+		c.retries.Add(1)
+		timer := time.NewTimer(c.retryDelay(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
 	}
-	defer resp.Body.Close()
+}
 
-	// Read response
-	body, err := io.ReadAll(resp.Body)
+// sendRequest sends a request once and checks the HTTP and API status of
+// the response
+func (c *Client) sendRequest(ctx context.Context, endpoint, form string) ([]byte, error) {
+	// Send to the first reachable mirror
+	post := c.post
+	if c.hedgeAfter > 0 && hedgedActions[endpoint] {
+		post = c.hedgedPost
+	}
+	body, resp, err := post(ctx, endpoint, form)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return nil, err
 	}
 
-	// Check HTTP status
+	// Check HTTP status; unknown actions are not found
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
 	}
 
 	// Parse basic response to check API status
@@ -152,20 +322,305 @@ func (c *Client) makeRequest(ctx context.Context, endpoint string, params map[st
 		return nil, fmt.Errorf("failed to parse response: %w", err)
 	}
 
+	c.observe(endpoint, apiResp.Status.Code)
+
 	if apiResp.Status.Code != successCode {
-		return nil, fmt.Errorf("API error: %s - %s", apiResp.Status.Code, apiResp.Status.Message)
+		return nil, &APIError{Action: endpoint, Code: apiResp.Status.Code, Message: apiResp.Status.Message}
 	}
 
 	return body, nil
 }
 
-// getDomains fetches and caches domain list
+// tc3Transport talks to DNSPod API 3.0 with Tencent Cloud API keys. It
+// takes the legacy form requests built by postTo, sends the equivalent API
+// 3.0 calls signed with TC3-HMAC-SHA256 and translates their responses
+// back, so that the rest of the client is the same for both APIs.
+type tc3Transport struct {
+	secretID  string
+	secretKey string
+	next      http.RoundTripper
+
+	// domainNames maps domain IDs to names, which most API 3.0 actions
+	// require alongside the ID
+	mutex       sync.Mutex
+	domainNames map[string]string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *tc3Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	form, err := readForm(req)
+	if err != nil {
+		return nil, err
+	}
+	action := path.Base(req.URL.Path)
+
+	out, status, err := t.translate(req, action, form)
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := json.Marshal(out)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode response: %w", err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        http.Header{"Content-Type": {"application/json"}},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// translate makes the API 3.0 call for a legacy action and returns the
+// legacy response with its HTTP status
+func (t *tc3Transport) translate(req *http.Request, action string, form url.Values) (any, int, error) {
+	if action == "Info.Version" {
+		out := legacyOK()
+		out.Status.Message = "3.0 (" + v3APIVersion + ")"
+		return out, http.StatusOK, nil
+	}
+
+	v3Action, ok := v3Actions[action]
+	if !ok {
+		return apiResponse{}, http.StatusNotFound, nil
+	}
+
+	payload, err := v3Request(action, form)
+	if err != nil {
+		var out apiResponse
+		out.Status.Code = "-1"
+		out.Status.Message = err.Error()
+		return out, http.StatusOK, nil
+	}
+	if id := form.Get("domain_id"); id != "" && action != "Domain.List" {
+		name, err := t.domainName(req, form.Get("lang"), id)
+		if err != nil {
+			return nil, 0, err
+		}
+		payload["Domain"] = name
+	}
+
+	data, apiErr, err := t.call(req, form.Get("lang"), v3Action, payload)
+	if err != nil {
+		return nil, 0, err
+	}
+	if apiErr != nil {
+		// An empty zone is an error in API 3.0
+		if action == "Record.List" && apiErr.Code == "ResourceNotFound.NoDataOfRecord" {
+			out := recordListResponse{Records: []record{}}
+			out.apiResponse = legacyOK()
+			return out, http.StatusOK, nil
+		}
+		out, status := legacyError(*apiErr)
+		return out, status, nil
+	}
+
+	out, err := v3Response(action, data)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to parse %s response: %w", v3Action, err)
+	}
+	if list, ok := out.(domainListResponse); ok {
+		t.rememberDomains(list.Domains)
+	}
+	return out, http.StatusOK, nil
+}
+
+// domainName returns the name of a domain by ID, listing the domains if it
+// is not known yet. Unknown IDs give an empty name, for the API to reject.
+func (t *tc3Transport) domainName(req *http.Request, lang, id string) (string, error) {
+	t.mutex.Lock()
+	name, ok := t.domainNames[id]
+	t.mutex.Unlock()
+	if ok {
+		return name, nil
+	}
+
+	payload := map[string]any{"Limit": v3PageSize}
+	data, apiErr, err := t.call(req, lang, v3Actions["Domain.List"], payload)
+	if err != nil {
+		return "", err
+	}
+	if apiErr != nil {
+		return "", nil
+	}
+	out, err := v3Response("Domain.List", data)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse domain list response: %w", err)
+	}
+	t.rememberDomains(out.(domainListResponse).Domains)
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return t.domainNames[id], nil
+}
+
+// rememberDomains records the names of listed domains
+func (t *tc3Transport) rememberDomains(domains []domain) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.domainNames == nil {
+		t.domainNames = make(map[string]string)
+	}
+	for _, d := range domains {
+		t.domainNames[string(d.ID)] = d.Name
+	}
+}
+
+// call sends a signed API 3.0 request to the host of req and returns the
+// Response object, or the API error if the call failed
+func (t *tc3Transport) call(req *http.Request, lang, action string, payload map[string]any) (json.RawMessage, *v3Error, error) {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to encode request: %w", err)
+	}
+
+	reqURL := *req.URL
+	reqURL.Path = "/"
+	v3Req, err := http.NewRequestWithContext(req.Context(), "POST", reqURL.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	v3Req.Host = req.Host
+	v3Req.Header.Set("User-Agent", req.Header.Get("User-Agent"))
+	v3Req.Header.Set("X-TC-Version", v3APIVersion)
+	if lang == "en" {
+		v3Req.Header.Set("X-TC-Language", "en-US")
+	} else {
+		v3Req.Header.Set("X-TC-Language", "zh-CN")
+	}
+	t.sign(v3Req, action, body, time.Now())
+
+	resp, err := t.next.RoundTrip(v3Req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status}
+	}
+
+	var envelope struct {
+		Response json.RawMessage `json:"Response"`
+	}
+	var result struct {
+		Error *v3Error `json:"Error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	if err := json.Unmarshal(envelope.Response, &result); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse response: %w", err)
+	}
+	return envelope.Response, result.Error, nil
+}
+
+// sign sets the headers of a TC3-HMAC-SHA256 signed request
+func (t *tc3Transport) sign(req *http.Request, action string, body []byte, now time.Time) {
+	const (
+		algorithm   = "TC3-HMAC-SHA256"
+		service     = "dnspod"
+		contentType = "application/json; charset=utf-8"
+	)
+
+	host := req.Host
+	if host == "" {
+		host = req.URL.Host
+	}
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	date := now.UTC().Format("2006-01-02")
+
+	signedHeaders := "content-type;host;x-tc-action"
+	canonicalRequest := strings.Join([]string{
+		"POST",
+		"/",
+		"",
+		"content-type:" + contentType + "\nhost:" + host + "\nx-tc-action:" + strings.ToLower(action) + "\n",
+		signedHeaders,
+		sha256Hex(body),
+	}, "\n")
+
+	scope := date + "/" + service + "/tc3_request"
+	stringToSign := strings.Join([]string{algorithm, timestamp, scope, sha256Hex([]byte(canonicalRequest))}, "\n")
+
+	key := hmacSHA256([]byte("TC3"+t.secretKey), date)
+	key = hmacSHA256(key, service)
+	key = hmacSHA256(key, "tc3_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-TC-Action", action)
+	req.Header.Set("X-TC-Timestamp", timestamp)
+	req.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		algorithm, t.secretID, scope, signedHeaders, signature))
+}
+
+// readForm reads the form body of a legacy request
+func readForm(req *http.Request) (url.Values, error) {
+	if req.Body == nil {
+		return url.Values{}, nil
+	}
+	defer req.Body.Close()
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request: %w", err)
+	}
+	form, err := url.ParseQuery(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse request: %w", err)
+	}
+	return form, nil
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// useAPIv3 makes the client talk to API 3.0, signing requests with the
+// given API keys. It is called once the endpoints are set up.
+func (c *Client) useAPIv3(secretID, secretKey string) {
+	for _, ep := range c.endpoints {
+		if _, ok := ep.httpClient.Transport.(*tc3Transport); ok {
+			continue
+		}
+		next := ep.httpClient.Transport
+		if next == nil {
+			next = http.DefaultTransport
+		}
+		ep.httpClient.Transport = &tc3Transport{secretID: secretID, secretKey: secretKey, next: next}
+	}
+
+	// API 3.0 has no Batch.* actions
+	c.caps.Batch = false
+}
+
+// getDomains returns the domain list, fetching it when it is not cached or
+// the cached list is older than the domain cache TTL
 func (c *Client) getDomains(ctx context.Context) ([]domain, error) {
 	c.mutex.RLock()
-	if len(c.domainList) > 0 {
+	if c.domainsFresh() {
 		domains := make([]domain, len(c.domainList))
 		copy(domains, c.domainList)
 		c.mutex.RUnlock()
+		c.domainHits.Add(1)
 		return domains, nil
 	}
 	c.mutex.RUnlock()
@@ -174,29 +629,96 @@ func (c *Client) getDomains(ctx context.Context) ([]domain, error) {
 	defer c.mutex.Unlock()
 
 	// Double-check after acquiring write lock
-	if len(c.domainList) > 0 {
+	if c.domainsFresh() {
 		domains := make([]domain, len(c.domainList))
 		copy(domains, c.domainList)
+		c.domainHits.Add(1)
 		return domains, nil
 	}
+	c.domainMisses.Add(1)
 
-	body, err := c.makeRequest(ctx, "Domain.List", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to list domains: %w", err)
+	return c.fetchDomains(ctx)
+}
+
+// refreshDomains fetches the domain list again, unless it was fetched less
+// than domainRefreshInterval ago
+func (c *Client) refreshDomains(ctx context.Context) ([]domain, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if !c.domainsFetched.IsZero() && time.Since(c.domainsFetched) < domainRefreshInterval {
+		domains := make([]domain, len(c.domainList))
+		copy(domains, c.domainList)
+		return domains, nil
 	}
+	c.domainMisses.Add(1)
 
-	var resp domainListResponse
-	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to parse domain list response: %w", err)
+	return c.fetchDomains(ctx)
+}
+
+// fetchDomains lists the domains and caches them. The caller must hold the
+// write lock.
+func (c *Client) fetchDomains(ctx context.Context) ([]domain, error) {
+	domainList, err := c.listDomains(ctx, nil)
+	if err != nil {
+		return nil, err
 	}
 
-	c.domainList = resp.Domains
+	c.domainList = domainList
+	c.domainsFetched = time.Now()
 	domains := make([]domain, len(c.domainList))
 	copy(domains, c.domainList)
 	return domains, nil
 }
 
-// getDomainID finds domain ID by domain name
+// domainsFresh reports whether the cached domain list can be used. The
+// caller must hold the lock.
+func (c *Client) domainsFresh() bool {
+	return !c.domainsFetched.IsZero() && time.Since(c.domainsFetched) < c.domainCacheTTL
+}
+
+// invalidateDomains drops the cached domain list
+func (c *Client) invalidateDomains() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.domainList = nil
+	c.domainsFetched = time.Time{}
+}
+
+// listDomains calls Domain.List with optional filter parameters, page by
+// page until domain_total domains were read
+func (c *Client) listDomains(ctx context.Context, filter map[string]string) ([]domain, error) {
+	var domains []domain
+	for {
+		params := map[string]string{
+			"offset": strconv.Itoa(len(domains)),
+			"length": strconv.Itoa(domainPageSize),
+		}
+		for key, value := range filter {
+			params[key] = value
+		}
+
+		body, err := c.makeRequest(ctx, "Domain.List", params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list domains: %w", err)
+		}
+
+		var resp domainListResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse domain list response: %w", err)
+		}
+
+		domains = append(domains, resp.Domains...)
+		if len(resp.Domains) == 0 || len(domains) >= resp.Info.DomainTotal {
+			return domains, nil
+		}
+	}
+}
+
+// getDomainID finds domain ID by domain name. If the domain is not in the
+// cached list, the list is fetched again, since the domain may have been
+// added to the account since.
 func (c *Client) getDomainID(ctx context.Context, domainName string) (string, error) {
 	domainName = strings.TrimSuffix(domainName, ".")
 
@@ -205,32 +727,228 @@ func (c *Client) getDomainID(ctx context.Context, domainName string) (string, er
 		return "", err
 	}
 
-	for _, domain := range domains {
-		if domain.Name == domainName {
-			return string(domain.ID), nil
+	d, ok := findDomain(domains, domainName)
+	if !ok {
+		if domains, err = c.refreshDomains(ctx); err != nil {
+			return "", err
 		}
+		d, ok = findDomain(domains, domainName)
+	}
+	if !ok {
+		return "", fmt.Errorf("domain %s not found in DNSPod account: %w", domainName, ErrZoneNotFound)
 	}
 
-	return "", fmt.Errorf("domain %s not found in DNSPod account", domainName)
+	if c.skipInactiveZones && d.Status != "enable" {
+		return "", &InactiveZoneError{Zone: domainName, Status: d.Status}
+	}
+	return string(d.ID), nil
 }
 
-// listRecords retrieves all DNS records for a domain
+// findDomain returns the domain with the given name
+func findDomain(domains []domain, name string) (domain, bool) {
+	for _, d := range domains {
+		if d.Name == name {
+			return d, true
+		}
+	}
+	return domain{}, false
+}
+
+// getDomainInfo fetches the details of a single domain
+func (c *Client) getDomainInfo(ctx context.Context, domainID string) (*domainInfo, error) {
+	params := map[string]string{
+		"domain_id": domainID,
+	}
+
+	body, err := c.makeRequest(ctx, "Domain.Info", params)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain info: %w", err)
+	}
+
+	var resp domainInfoResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse domain info response: %w", err)
+	}
+
+	return &resp.Domain, nil
+}
+
+// listRecords retrieves all DNS records for a domain, from the cache when
+// record caching is enabled
 func (c *Client) listRecords(ctx context.Context, domainID string) ([]record, error) {
+	if c.records != nil {
+		if records, ok := c.records.get(domainID); ok {
+			return records, nil
+		}
+	}
+
+	records, err := c.fetchRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.records != nil {
+		c.records.put(domainID, records)
+	}
+	return records, nil
+}
+
+// recordsPointingAt returns the records of a domain whose value is value.
+// With record caching enabled this is answered from the reverse index
+// while the domain is cached; otherwise the listing is fetched, and
+// cached for the next lookup.
+func (c *Client) recordsPointingAt(ctx context.Context, domainID, value string) ([]record, error) {
+	if c.records != nil {
+		if records, ok := c.records.pointingAt(domainID, value); ok {
+			return records, nil
+		}
+	}
+
+	records, err := c.listRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []record
+	for _, rec := range records {
+		if pointsAt(rec, value) {
+			matches = append(matches, rec)
+		}
+	}
+	return matches, nil
+}
+
+// invalidateRecords drops cached records of a domain after a write
+func (c *Client) invalidateRecords(domainID string) {
+	if c.records != nil {
+		c.records.invalidate(domainID)
+	}
+}
+
+// fetchRecords calls Record.List for all records of a domain
+func (c *Client) fetchRecords(ctx context.Context, domainID string) ([]record, error) {
+	return c.queryRecords(ctx, domainID, "", "")
+}
+
+// queryRecords calls Record.List, page by page until record_total records
+// were read. A non-empty subDomain or recordType is passed to DNSPod as a
+// filter.
+func (c *Client) queryRecords(ctx context.Context, domainID, subDomain, recordType string) ([]record, error) {
+	var records []record
+	for {
+		params := map[string]string{
+			"domain_id": domainID,
+			"offset":    strconv.Itoa(len(records)),
+			"length":    strconv.Itoa(recordPageSize),
+		}
+		if subDomain != "" {
+			params["sub_domain"] = subDomain
+		}
+		if recordType != "" {
+			params["record_type"] = recordType
+		}
+
+		body, err := c.makeRequest(ctx, "Record.List", params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list records: %w", err)
+		}
+
+		var resp recordListResponse
+		if err := json.Unmarshal(body, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse record list response: %w", err)
+		}
+
+		records = append(records, resp.Records...)
+
+		total, _ := resp.Info.RecordTotal.Int64()
+		if len(resp.Records) == 0 || int64(len(records)) >= total {
+			return records, nil
+		}
+	}
+}
+
+// findRecords returns the records of a domain with the given name and
+// type. They are taken from the record cache when caching is enabled and
+// looked up with Record.List filters otherwise.
+func (c *Client) findRecords(ctx context.Context, domainID, subDomain, recordType string) ([]record, error) {
+	if c.records == nil {
+		return c.queryRecords(ctx, domainID, strings.ToLower(subDomain), strings.ToUpper(recordType))
+	}
+
+	records, err := c.listRecords(ctx, domainID)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []record
+	for _, rec := range records {
+		if strings.EqualFold(rec.Name, subDomain) && (recordType == "" || strings.EqualFold(rec.Type, recordType)) {
+			matches = append(matches, rec)
+		}
+	}
+	return matches, nil
+}
+
+// lookupRecords returns the existing records a write of records needs to
+// see: those of the RRsets they belong to
+func (c *Client) lookupRecords(ctx context.Context, domainID, zone string, records []libdns.Record) ([]record, error) {
+	recs := make([]record, len(records))
+	for i, libRec := range records {
+		recs[i] = convertRecordData(libRec, zone)
+	}
+	return c.lookupRRsets(ctx, domainID, recs)
+}
+
+// lookupRRsets returns the existing records with the names and types of
+// recs. Each RRset is looked up on its own, unless there are so many that
+// listing the whole zone is cheaper or the zone is cached anyway.
+func (c *Client) lookupRRsets(ctx context.Context, domainID string, recs []record) ([]record, error) {
+	type rrset struct{ name, typ string }
+
+	var rrsets []rrset
+	seen := make(map[rrset]bool)
+	for _, rec := range recs {
+		key := rrset{strings.ToLower(rec.Name), strings.ToUpper(rec.Type)}
+		if !seen[key] {
+			seen[key] = true
+			rrsets = append(rrsets, key)
+		}
+	}
+
+	if c.records != nil || len(rrsets) > maxRRsetLookups {
+		return c.listRecords(ctx, domainID)
+	}
+
+	var existing []record
+	for _, set := range rrsets {
+		matches, err := c.findRecords(ctx, domainID, set.name, set.typ)
+		if err != nil {
+			return nil, err
+		}
+		existing = append(existing, matches...)
+	}
+	return existing, nil
+}
+
+// getRecord fetches a single DNS record
+func (c *Client) getRecord(ctx context.Context, domainID, recordID string) (*record, error) {
 	params := map[string]string{
 		"domain_id": domainID,
+		"record_id": recordID,
 	}
 
-	body, err := c.makeRequest(ctx, "Record.List", params)
+	body, err := c.makeRequest(ctx, "Record.Info", params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to list records: %w", err)
+		return nil, fmt.Errorf("failed to get record: %w", err)
 	}
 
-	var resp recordListResponse
+	var resp recordInfoResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
-		return nil, fmt.Errorf("failed to parse record list response: %w", err)
+		return nil, fmt.Errorf("failed to parse record info response: %w", err)
 	}
 
-	return resp.Records, nil
+	rec := resp.Record.record()
+	return &rec, nil
 }
 
 // createRecord creates a new DNS record
@@ -239,9 +957,9 @@ func (c *Client) createRecord(ctx context.Context, domainID string, rec record)
 		"domain_id":   domainID,
 		"sub_domain":  rec.Name,
 		"record_type": rec.Type,
-		"record_line": "默认",
 		"value":       rec.Value,
 	}
+	c.lineParams(params, rec)
 
 	if rec.TTL != "" {
 		params["ttl"] = rec.TTL
@@ -253,7 +971,12 @@ func (c *Client) createRecord(ctx context.Context, domainID string, rec record)
 		params["mx"] = rec.MX
 	}
 
+	if rec.Weight != "" {
+		params["weight"] = rec.Weight
+	}
+
 	body, err := c.makeRequest(ctx, "Record.Create", params)
+	c.invalidateRecords(domainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create record: %w", err)
 	}
@@ -263,7 +986,8 @@ func (c *Client) createRecord(ctx context.Context, domainID string, rec record)
 		return nil, fmt.Errorf("failed to parse create record response: %w", err)
 	}
 
-	return &resp.Record, nil
+	created := completeRecord(resp.Record, rec)
+	return &created, nil
 }
 
 // updateRecord updates an existing DNS record
@@ -273,9 +997,9 @@ func (c *Client) updateRecord(ctx context.Context, domainID, recordID string, re
 		"record_id":   recordID,
 		"sub_domain":  rec.Name,
 		"record_type": rec.Type,
-		"record_line": "默认",
 		"value":       rec.Value,
 	}
+	c.lineParams(params, rec)
 
 	if rec.TTL != "" {
 		params["ttl"] = rec.TTL
@@ -285,7 +1009,12 @@ func (c *Client) updateRecord(ctx context.Context, domainID, recordID string, re
 		params["mx"] = rec.MX
 	}
 
+	if rec.Weight != "" {
+		params["weight"] = rec.Weight
+	}
+
 	body, err := c.makeRequest(ctx, "Record.Modify", params)
+	c.invalidateRecords(domainID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update record: %w", err)
 	}
@@ -295,7 +1024,86 @@ func (c *Client) updateRecord(ctx context.Context, domainID, recordID string, re
 		return nil, fmt.Errorf("failed to parse update record response: %w", err)
 	}
 
-	return &resp.Record, nil
+	updated := completeRecord(resp.Record, rec)
+	if updated.ID == "" {
+		updated.ID = recordID
+	}
+	return &updated, nil
+}
+
+// ddnsRecord points an A record at a new address with Record.Ddns, which
+// DNSPod rate limits separately from Record.Modify
+func (c *Client) ddnsRecord(ctx context.Context, domainID, recordID string, rec record) (*record, error) {
+	params := map[string]string{
+		"domain_id":  domainID,
+		"record_id":  recordID,
+		"sub_domain": rec.Name,
+		"value":      rec.Value,
+	}
+	c.lineParams(params, rec)
+
+	body, err := c.makeRequest(ctx, "Record.Ddns", params)
+	c.invalidateRecords(domainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update dynamic record: %w", err)
+	}
+
+	var resp recordResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse dynamic record response: %w", err)
+	}
+
+	updated := completeRecord(resp.Record, rec)
+	if updated.ID == "" {
+		updated.ID = recordID
+	}
+	return &updated, nil
+}
+
+// record converts a Record.Info record to the Record.List shape
+func (r recordInfo) record() record {
+	return record{
+		ID:        r.ID,
+		Name:      r.SubDomain,
+		Type:      r.RecordType,
+		Line:      r.RecordLine,
+		LineID:    r.RecordLineID,
+		Value:     r.Value,
+		Weight:    rawWeight(r.Weight),
+		MX:        r.MX,
+		TTL:       r.TTL,
+		Enabled:   r.Enabled,
+		Remark:    r.Remark,
+		UpdatedOn: r.UpdatedOn,
+	}
+}
+
+// completeRecord fills in the fields DNSPod does not echo back in create and
+// modify responses (which only carry id, name, value and status) from the
+// record that was sent
+func completeRecord(resp, sent record) record {
+	if resp.Name == "" {
+		resp.Name = sent.Name
+	}
+	if resp.Type == "" {
+		resp.Type = sent.Type
+	}
+	if resp.Value == "" {
+		resp.Value = sent.Value
+	}
+	if resp.TTL == "" {
+		resp.TTL = sent.TTL
+	}
+	if resp.MX == "" {
+		resp.MX = sent.MX
+	}
+	if resp.Line == "" {
+		resp.Line = sent.Line
+	}
+	if resp.Weight == "" {
+		resp.Weight = sent.Weight
+	}
+	return resp
 }
 
 // deleteRecord deletes a DNS record
@@ -306,6 +1114,7 @@ func (c *Client) deleteRecord(ctx context.Context, domainID, recordID string) er
 	}
 
 	_, err := c.makeRequest(ctx, "Record.Remove", params)
+	c.invalidateRecords(domainID)
 	if err != nil {
 		return fmt.Errorf("failed to delete record: %w", err)
 	}
@@ -348,67 +1157,156 @@ func makeAbsoluteName(name, zone string) string {
 
 // convertToLibDNSRecord converts a DNSPod record to libdns.Record format
 func convertToLibDNSRecord(rec record, zone string) libdns.Record {
-	ttl, _ := strconv.ParseInt(rec.TTL, 10, 64)
-	ttlDuration := time.Duration(ttl) * time.Second
+	ttlDuration, _ := ParseTTL(rec.TTL)
+
+	absoluteName := NormalizeName(makeAbsoluteName(rec.Name, zone))
+	meta := RecordMetadata{
+		ID:        rec.ID,
+		System:    isSystemRecord(rec),
+		UpdatedOn: parseTimestamp(rec.UpdatedOn),
+	}
+	meta.Status, meta.Enabled = parseRecordStatus(rec.Status, rec.Enabled)
+	meta.Remark = rec.Remark
+	meta.Labels, _ = ParseLabels(rec.Remark)
+	meta.Line = rec.Line
+	meta.LineID = rec.LineID
+	if weight, ok, err := ParseWeight(rec.Weight); ok && err == nil {
+		meta.Weight = &weight
+	}
 
-	absoluteName := makeAbsoluteName(rec.Name, zone)
+	// Records that cannot be returned as a specific type keep their data
+	// as stored, with the metadata
+	generic := GenericRecord{
+		Name:         absoluteName,
+		Type:         rec.Type,
+		TTL:          ttlDuration,
+		Data:         rec.Value,
+		ProviderData: meta,
+	}
 
 	// Return specific libdns record types based on the DNS record type
 	switch strings.ToUpper(rec.Type) {
 	case "A", "AAAA":
 		ip, err := netip.ParseAddr(rec.Value)
 		if err != nil {
-			// Fallback to RR if IP parsing fails
-			return libdns.RR{
-				Name: absoluteName,
-				Type: rec.Type,
-				TTL:  ttlDuration,
-				Data: rec.Value,
-			}
+			// Fallback to the stored data if IP parsing fails
+			return generic
 		}
 		return libdns.Address{
-			Name: absoluteName,
-			IP:   ip,
-			TTL:  ttlDuration,
+			Name:         absoluteName,
+			IP:           ip,
+			TTL:          ttlDuration,
+			ProviderData: meta,
 		}
 	case "TXT":
 		return libdns.TXT{
-			Name: absoluteName,
-			Text: rec.Value,
-			TTL:  ttlDuration,
+			Name:         absoluteName,
+			Text:         rec.Value,
+			TTL:          ttlDuration,
+			ProviderData: meta,
 		}
 	case "CNAME":
 		return libdns.CNAME{
-			Name:   absoluteName,
-			Target: rec.Value,
-			TTL:    ttlDuration,
+			Name:         absoluteName,
+			Target:       rec.Value,
+			TTL:          ttlDuration,
+			ProviderData: meta,
 		}
 	case "MX":
-		preference := 0
-		if rec.MX != "" {
-			if p, err := strconv.Atoi(rec.MX); err == nil {
-				preference = p
-			}
-		}
+		preference, _ := ParseMXPreference(rec.MX)
 		return libdns.MX{
-			Name:       absoluteName,
-			Target:     rec.Value,
-			Preference: uint16(preference),
-			TTL:        ttlDuration,
+			Name:         absoluteName,
+			Target:       rec.Value,
+			Preference:   preference,
+			TTL:          ttlDuration,
+			ProviderData: meta,
 		}
-	default:
-		// For all other record types (NS, SOA, SRV, etc.), use RR
-		return libdns.RR{
+	case "NS":
+		return libdns.NS{
+			Name:         absoluteName,
+			Target:       rec.Value,
+			TTL:          ttlDuration,
+			ProviderData: meta,
+		}
+	case "TLSA":
+		tlsa, err := parseTLSA(absoluteName, ttlDuration, rec.Value)
+		if err != nil {
+			return generic
+		}
+		tlsa.ProviderData = meta
+		return tlsa
+	case "NAPTR":
+		naptr, err := parseNAPTR(absoluteName, ttlDuration, rec.Value)
+		if err != nil {
+			return generic
+		}
+		naptr.ProviderData = meta
+		return naptr
+	case "SRV":
+		srv, err := ParseSRVValue(rec.Value)
+		if err != nil {
+			return generic
+		}
+		if len(strings.Fields(rec.Value)) == 3 {
+			// DNSPod keeps the priority in the mx field
+			srv.Priority, _ = ParseMXPreference(rec.MX)
+		}
+		parsed, err := libdns.RR{
 			Name: absoluteName,
-			Type: rec.Type,
+			Type: "SRV",
 			TTL:  ttlDuration,
-			Data: rec.Value,
+			Data: fmt.Sprintf("%d %d %d %s", srv.Priority, srv.Weight, srv.Port, srv.Target),
+		}.Parse()
+		if err != nil {
+			return generic
+		}
+		return withMetadata(parsed, meta)
+	case "CAA":
+		caa, err := ParseCAAValue(rec.Value)
+		if err != nil {
+			return generic
+		}
+		return libdns.CAA{
+			Name:         absoluteName,
+			TTL:          ttlDuration,
+			Flags:        caa.Flags,
+			Tag:          caa.Tag,
+			Value:        caa.Value,
+			ProviderData: meta,
+		}
+	case "HTTPS", "SVCB":
+		parsed, err := libdns.RR{
+			Name: absoluteName,
+			Type: strings.ToUpper(rec.Type),
+			TTL:  ttlDuration,
+			Data: serviceBindingData(rec),
+		}.Parse()
+		if err != nil {
+			return generic
 		}
+		return withMetadata(parsed, meta)
+	default:
+		// For all other record types, keep the data as stored
+		return generic
 	}
 }
 
 // convertFromLibDNSRecord converts a libdns.Record to DNSPod record format
 func convertFromLibDNSRecord(libRec libdns.Record, zone string) record {
+	rec := convertRecordData(libRec, zone)
+	rec.Remark, _ = inputRemark(libRec)
+	if meta, ok := recordMetadata(libRec); ok {
+		rec.Line = meta.Line
+		rec.LineID = meta.LineID
+		if meta.Weight != nil {
+			rec.Weight = strconv.Itoa(*meta.Weight)
+		}
+	}
+	return rec
+}
+
+// convertRecordData converts the name, type, value and TTL of a record
+func convertRecordData(libRec libdns.Record, zone string) record {
 	// Handle different record types
 	switch r := libRec.(type) {
 	case libdns.Address:
@@ -432,6 +1330,13 @@ func convertFromLibDNSRecord(libRec libdns.Record, zone string) record {
 			Value: r.Target,
 			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
 		}
+	case libdns.NS:
+		return record{
+			Name:  extractRecordName(r.Name, zone),
+			Type:  "NS",
+			Value: r.Target,
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
 	case libdns.MX:
 		return record{
 			Name:  extractRecordName(r.Name, zone),
@@ -440,7 +1345,56 @@ func convertFromLibDNSRecord(libRec libdns.Record, zone string) record {
 			MX:    strconv.Itoa(int(r.Preference)),
 			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
 		}
+	case libdns.SRV:
+		// DNSPod takes the priority in the mx field and the rest of the
+		// data as the value
+		return record{
+			Name:  extractRecordName(r.RR().Name, zone),
+			Type:  "SRV",
+			Value: fmt.Sprintf("%d %d %s", r.Weight, r.Port, r.Target),
+			MX:    strconv.Itoa(int(r.Priority)),
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
+	case libdns.CAA:
+		return record{
+			Name:  extractRecordName(r.Name, zone),
+			Type:  "CAA",
+			Value: fmt.Sprintf("%d %s %s", r.Flags, strings.ToLower(r.Tag), strconv.Quote(r.Value)),
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
+	case libdns.ServiceBinding:
+		rr := r.RR()
+		return record{
+			Name:  extractRecordName(rr.Name, zone),
+			Type:  rr.Type,
+			Value: formatServiceBinding(r.Priority, r.Target, r.Params),
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
+	case TLSA:
+		return record{
+			Name:  extractRecordName(r.Name, zone),
+			Type:  "TLSA",
+			Value: r.RR().Data,
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
+	case NAPTR:
+		return record{
+			Name:  extractRecordName(r.Name, zone),
+			Type:  "NAPTR",
+			Value: r.RR().Data,
+			TTL:   strconv.Itoa(int(r.TTL.Seconds())),
+		}
+	case GenericRecord:
+		return convertRecordData(r.RR(), zone)
 	case libdns.RR:
+		switch strings.ToUpper(r.Type) {
+		case "MX", "SRV", "CAA", "HTTPS", "SVCB":
+			// Split the data like the parsed type would
+			r.Type = strings.ToUpper(r.Type)
+			if parsed, err := r.Parse(); err == nil {
+				return convertRecordData(parsed, zone)
+			}
+		}
 		return record{
 			Name:  extractRecordName(r.Name, zone),
 			Type:  r.Type,
@@ -459,6 +1413,95 @@ func convertFromLibDNSRecord(libRec libdns.Record, zone string) record {
 	}
 }
 
+// serviceBindingData returns the data of an HTTPS or SVCB record in
+// presentation format. Values without a leading priority take it from the
+// mx field.
+func serviceBindingData(rec record) string {
+	fields := strings.Fields(rec.Value)
+	if len(fields) > 0 {
+		if _, err := strconv.ParseUint(fields[0], 10, 16); err != nil && rec.MX != "" {
+			return rec.MX + " " + rec.Value
+		}
+	}
+	return rec.Value
+}
+
+// svcParamKeys are the SvcParam keys in the order of their key numbers
+// (RFC 9460 section 14.3.2)
+var svcParamKeys = []string{"mandatory", "alpn", "no-default-alpn", "port", "ipv4hint", "ech", "ipv6hint"}
+
+// formatServiceBinding returns the value of an HTTPS or SVCB record. The
+// params are written in key order, since libdns writes them in map order
+// and the value would otherwise change from one write to the next. Alias
+// mode records (priority 0) carry no params.
+func formatServiceBinding(priority uint16, target string, params libdns.SvcParams) string {
+	value := fmt.Sprintf("%d %s", priority, target)
+	if priority == 0 || len(params) == 0 {
+		return value
+	}
+
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := svcParamRank(keys[i]), svcParamRank(keys[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+
+	for _, key := range keys {
+		value += " " + libdns.SvcParams{key: params[key]}.String()
+	}
+	return value
+}
+
+// svcParamRank returns the key number of a SvcParam key, named or keyNNNNN
+func svcParamRank(key string) int {
+	for i, name := range svcParamKeys {
+		if key == name {
+			return i
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimPrefix(key, "key")); err == nil && strings.HasPrefix(key, "key") {
+		return n
+	}
+	return math.MaxInt
+}
+
+// canonicalServiceBinding reformats the value of an HTTPS or SVCB record
+// like formatServiceBinding. It reports false if the value is malformed.
+func canonicalServiceBinding(value string) (string, bool) {
+	fields := strings.SplitN(strings.TrimSpace(value), " ", 3)
+	if len(fields) < 2 {
+		return "", false
+	}
+	priority, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return "", false
+	}
+
+	var params libdns.SvcParams
+	if len(fields) == 3 {
+		if params, err = libdns.ParseSvcParams(fields[2]); err != nil {
+			return "", false
+		}
+	}
+	return formatServiceBinding(uint16(priority), strings.ToLower(fields[1]), params), true
+}
+
+// usesMX reports whether records of a type carry a number in the mx field:
+// the preference of MX records and the priority of SRV records
+func usesMX(typ string) bool {
+	switch strings.ToUpper(typ) {
+	case "MX", "SRV":
+		return true
+	}
+	return false
+}
+
 // getRecordType determines A or AAAA based on IP address
 func getRecordType(ip fmt.Stringer) string {
 	ipStr := ip.String()
@@ -0,0 +1,178 @@
+package dnspod
+
+import (
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestConvertRoundTrip(t *testing.T) {
+	const zone = "example.com."
+
+	params, err := libdns.ParseSvcParams(`alpn=h2,h3 port=8443`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		in     libdns.Record
+		sent   record
+		rrName string
+		rrData string
+	}{
+		{
+			name:   "SRV",
+			in:     libdns.SRV{Service: "sip", Transport: "tcp", Name: "example.com.", TTL: time.Minute, Priority: 10, Weight: 20, Port: 5060, Target: "sip.example.com."},
+			sent:   record{Name: "_sip._tcp", Type: "SRV", Value: "20 5060 sip.example.com.", MX: "10", TTL: "60"},
+			rrName: "_sip._tcp.example.com.",
+			rrData: "10 20 5060 sip.example.com.",
+		},
+		{
+			name:   "SRV subdomain",
+			in:     libdns.SRV{Service: "xmpp", Transport: "udp", Name: "chat.example.com.", TTL: time.Minute, Weight: 5, Port: 5222, Target: "."},
+			sent:   record{Name: "_xmpp._udp.chat", Type: "SRV", Value: "5 5222 .", MX: "0", TTL: "60"},
+			rrName: "_xmpp._udp.chat.example.com.",
+			rrData: "0 5 5222 .",
+		},
+		{
+			name:   "SRV as RR",
+			in:     libdns.RR{Name: "_sip._udp", Type: "srv", TTL: time.Minute, Data: "1 2 3 t.example.com."},
+			sent:   record{Name: "_sip._udp", Type: "SRV", Value: "2 3 t.example.com.", MX: "1", TTL: "60"},
+			rrName: "_sip._udp.example.com.",
+			rrData: "1 2 3 t.example.com.",
+		},
+		{
+			name:   "CAA",
+			in:     libdns.CAA{Name: "example.com.", TTL: time.Minute, Flags: 128, Tag: "issue", Value: "letsencrypt.org; validationmethods=dns-01"},
+			sent:   record{Name: "@", Type: "CAA", Value: `128 issue "letsencrypt.org; validationmethods=dns-01"`, TTL: "60"},
+			rrName: "example.com.",
+			rrData: `128 issue "letsencrypt.org; validationmethods=dns-01"`,
+		},
+		{
+			name:   "NS",
+			in:     libdns.NS{Name: "sub.example.com.", TTL: time.Minute, Target: "ns1.example.net."},
+			sent:   record{Name: "sub", Type: "NS", Value: "ns1.example.net.", TTL: "60"},
+			rrName: "sub.example.com.",
+			rrData: "ns1.example.net.",
+		},
+		{
+			name:   "HTTPS",
+			in:     libdns.ServiceBinding{Scheme: "https", Name: "www.example.com.", TTL: time.Minute, Priority: 1, Target: ".", Params: params},
+			sent:   record{Name: "www", Type: "HTTPS", Value: "1 . alpn=h2,h3 port=8443", TTL: "60"},
+			rrName: "www.example.com.",
+			rrData: "1 . alpn=h2,h3 port=8443",
+		},
+		{
+			name:   "HTTPS alias",
+			in:     libdns.ServiceBinding{Scheme: "https", Name: "example.com.", TTL: time.Minute, Target: "cdn.example.net."},
+			sent:   record{Name: "@", Type: "HTTPS", Value: "0 cdn.example.net.", TTL: "60"},
+			rrName: "example.com.",
+			rrData: "0 cdn.example.net. ",
+		},
+		{
+			name:   "SVCB",
+			in:     libdns.ServiceBinding{Scheme: "dns", Name: "example.com.", TTL: time.Minute, Priority: 1, Target: "dns.example.net.", Params: libdns.SvcParams{"alpn": {"dot"}}},
+			sent:   record{Name: "_dns", Type: "SVCB", Value: "1 dns.example.net. alpn=dot", TTL: "60"},
+			rrName: "_dns.example.com.",
+			rrData: "1 dns.example.net. alpn=dot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := convertFromLibDNSRecord(tt.in, zone)
+			if sent != tt.sent {
+				t.Errorf("sent %+v, want %+v", sent, tt.sent)
+			}
+			// Unknown endpoints only get the type-specific checks
+			if err := (&Client{}).validateRecord(sent); err != nil {
+				t.Errorf("sent record is invalid: %v", err)
+			}
+
+			back := convertToLibDNSRecord(sent, zone)
+			if _, ok := back.(GenericRecord); ok {
+				t.Fatalf("converted back to a generic record: %+v", back)
+			}
+			rr := back.RR()
+			if rr.Name != tt.rrName || !sameRecordValue(rr.Type, rr.Data, tt.rrData) || rr.TTL != time.Minute {
+				t.Errorf("converted back to %+v, want name %q, data %q", rr, tt.rrName, tt.rrData)
+			}
+			if again := convertFromLibDNSRecord(back, zone); again != tt.sent {
+				t.Errorf("sent again %+v, want %+v", again, tt.sent)
+			}
+		})
+	}
+}
+
+func TestConvertFromAPI(t *testing.T) {
+	const zone = "example.com."
+
+	tests := []struct {
+		name   string
+		rec    record
+		rrData string
+	}{
+		{"SRV priority in mx", record{Name: "_a._tcp", Type: "SRV", Value: "6 7 t.example.com.", MX: "5", TTL: "600"}, "5 6 7 t.example.com."},
+		{"SRV priority in value", record{Name: "_a._tcp", Type: "SRV", Value: "5 6 7 t.example.com.", MX: "0", TTL: "600"}, "5 6 7 t.example.com."},
+		{"CAA unquoted", record{Name: "@", Type: "CAA", Value: "0 issue letsencrypt.org", TTL: "600"}, `0 issue "letsencrypt.org"`},
+		{"HTTPS priority in mx", record{Name: "@", Type: "HTTPS", Value: ". alpn=h2", MX: "1", TTL: "600"}, "1 . alpn=h2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			back := convertToLibDNSRecord(tt.rec, zone)
+			if _, ok := back.(GenericRecord); ok {
+				t.Fatalf("converted to a generic record: %+v", back)
+			}
+			if rr := back.RR(); !sameRecordValue(rr.Type, rr.Data, tt.rrData) {
+				t.Errorf("data %q, want %q", rr.Data, tt.rrData)
+			}
+		})
+	}
+}
+
+func TestConvertFallbackKeepsMetadata(t *testing.T) {
+	const zone = "example.com."
+
+	tests := []struct {
+		name string
+		rec  record
+	}{
+		{"unparseable A", record{Name: "www", Type: "A", Value: "bogus"}},
+		{"unparseable AAAA", record{Name: "www", Type: "AAAA", Value: "bogus"}},
+		{"unparseable SRV", record{Name: "_a._tcp", Type: "SRV", Value: "x y z"}},
+		{"unparseable CAA", record{Name: "@", Type: "CAA", Value: "bogus"}},
+		{"unparseable HTTPS", record{Name: "@", Type: "HTTPS", Value: "bogus bogus=="}},
+		{"unparseable TLSA", record{Name: "_443._tcp", Type: "TLSA", Value: "bogus"}},
+		{"unparseable NAPTR", record{Name: "sip", Type: "NAPTR", Value: "bogus"}},
+		{"other type", record{Name: "www", Type: "URL", Value: "https://example.net"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := tt.rec
+			rec.ID, rec.TTL, rec.Line, rec.LineID, rec.Weight = "42", "600", "电信", "10=0", "5"
+
+			back := convertToLibDNSRecord(rec, zone)
+			generic, ok := back.(GenericRecord)
+			if !ok {
+				t.Fatalf("converted to %T, want GenericRecord", back)
+			}
+			if generic.Type != rec.Type || generic.Data != rec.Value || generic.TTL != 10*time.Minute {
+				t.Errorf("converted to %+v", generic)
+			}
+
+			meta, ok := recordMetadata(back)
+			if !ok || meta.ID != "42" || meta.Line != "电信" || meta.LineID != "10=0" || meta.Weight == nil || *meta.Weight != 5 {
+				t.Fatalf("metadata %+v, want the record's", meta)
+			}
+
+			sent := convertFromLibDNSRecord(back, zone)
+			if sent.Name != rec.Name || sent.Type != rec.Type || sent.Value != rec.Value || sent.Line != "电信" || sent.Weight != "5" {
+				t.Errorf("sent back as %+v", sent)
+			}
+		})
+	}
+}
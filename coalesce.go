@@ -0,0 +1,112 @@
+package dnspod
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// coalescer buffers SetRecords calls per zone until the window closes
+type coalescer struct {
+	mutex   sync.Mutex
+	batches map[string]*coalescedBatch
+}
+
+// coalescedBatch is the merged set of records pending for a zone
+type coalescedBatch struct {
+	// sets holds the records last set for each RRset, in the order the
+	// RRsets were first set
+	sets  map[rrsetKey][]libdns.Record
+	order []rrsetKey
+
+	// done is closed once the batch was flushed and records, ranges,
+	// changes and err are set. ranges maps each RRset to the indexes of
+	// its records in records.
+	done    chan struct{}
+	records []libdns.Record
+	ranges  map[rrsetKey][2]int
+	changes []Change
+	err     error
+}
+
+// coalesceSet adds records to the zone's pending batch, starting one if
+// needed, and waits for it to be flushed. Like consecutive SetRecords
+// calls, the records replace those pending for the same RRsets (name, type
+// and line), so each RRset is set to the records of the last call that
+// gave it.
+func (p *Provider) coalesceSet(ctx context.Context, zone string, records []libdns.Record) ([]Change, error) {
+	client := p.getClient()
+	c := &client.pendingSets
+	zoneKey := strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	sets := make(map[rrsetKey][]libdns.Record)
+	var order []rrsetKey
+	for _, libRec := range records {
+		key := client.inputRRset(convertFromLibDNSRecord(libRec, zone))
+		if _, ok := sets[key]; !ok {
+			order = append(order, key)
+		}
+		sets[key] = append(sets[key], libRec)
+	}
+
+	c.mutex.Lock()
+	if c.batches == nil {
+		c.batches = make(map[string]*coalescedBatch)
+	}
+	batch, ok := c.batches[zoneKey]
+	if !ok {
+		batch = &coalescedBatch{sets: make(map[rrsetKey][]libdns.Record), done: make(chan struct{})}
+		c.batches[zoneKey] = batch
+
+		// The flush outlives the caller that started it, since later
+		// callers depend on it too, and must not carry its context values
+		// such as the zone lock marker
+		time.AfterFunc(p.CoalesceWindow, func() {
+			c.mutex.Lock()
+			delete(c.batches, zoneKey)
+			c.mutex.Unlock()
+
+			batch.ranges = make(map[rrsetKey][2]int)
+			for _, key := range batch.order {
+				start := len(batch.records)
+				batch.records = append(batch.records, batch.sets[key]...)
+				batch.ranges[key] = [2]int{start, len(batch.records)}
+			}
+			batch.changes, batch.err = p.setRecords(context.Background(), zone, batch.records)
+			close(batch.done)
+		})
+	}
+	for _, key := range order {
+		if _, ok := batch.sets[key]; !ok {
+			batch.order = append(batch.order, key)
+		}
+		batch.sets[key] = sets[key]
+	}
+	c.mutex.Unlock()
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// setRecords returns one change per record, in order, up to the first
+	// failure, followed by the deletes of the replaced RRsets. Each caller
+	// gets the changes of the RRsets it set, as flushed.
+	var changes []Change
+	for _, key := range order {
+		r := batch.ranges[key]
+		changes = append(changes, batch.changes[min(r[0], len(batch.changes)):min(r[1], len(batch.changes))]...)
+	}
+	for _, change := range batch.changes[min(len(batch.records), len(batch.changes)):] {
+		if change.Op == ChangeDelete {
+			if _, ok := sets[client.storedRRset(*change.existing)]; ok {
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes, batch.err
+}
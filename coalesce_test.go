@@ -0,0 +1,63 @@
+package dnspod_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+func TestCoalesceSetRRset(t *testing.T) {
+	backend := newFakeDNSPod("example.com")
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL, CoalesceWindow: 200 * time.Millisecond}
+	ctx := context.Background()
+
+	txt := func(name, text string) libdns.Record {
+		return libdns.TXT{Name: name, TTL: 10 * time.Minute, Text: text}
+	}
+	if _, err := provider.AppendRecords(ctx, "example.com.", []libdns.Record{txt("test", "old"), txt("other", "keep")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The result is the same as setting them one after the other: the
+	// last call to set an RRset wins
+	inputs := [][]libdns.Record{
+		{txt("test", "a")},
+		{txt("test", "b"), txt("test", "c")},
+		{txt("second", "z")},
+	}
+	results := make([][]dnspod.Change, len(inputs))
+	var wg sync.WaitGroup
+	for i, records := range inputs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			changes, err := provider.SetRecordsWithChanges(ctx, "example.com.", records)
+			if err != nil {
+				t.Errorf("set %d: %v", i, err)
+			}
+			results[i] = changes
+		}()
+		// Join the batch in order, well within the window
+		time.Sleep(10 * time.Millisecond)
+	}
+	wg.Wait()
+
+	for i, want := range []int{2, 2, 1} {
+		if len(results[i]) != want {
+			t.Errorf("set %d returned %d changes, want %d: %+v", i, len(results[i]), want, results[i])
+		}
+	}
+
+	if got, want := txtRecords(backend), "默认:b 默认:c 默认:keep 默认:z"; got != want {
+		t.Errorf("TXT records %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,186 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// placeholderPattern matches Caddy-style {env.NAME} and {file.PATH}
+// placeholders
+var placeholderPattern = regexp.MustCompile(`\{(env|file)\.([^{}]+)\}`)
+
+// expandPlaceholders replaces {env.NAME} with the environment variable and
+// {file.PATH} with the trimmed contents of the file. It fails on unset
+// variables and unreadable files.
+func expandPlaceholders(s string) (string, error) {
+	var firstErr error
+	expanded := placeholderPattern.ReplaceAllStringFunc(s, func(m string) string {
+		parts := placeholderPattern.FindStringSubmatch(m)
+		switch parts[1] {
+		case "env":
+			value, ok := os.LookupEnv(parts[2])
+			if !ok && firstErr == nil {
+				firstErr = fmt.Errorf("environment variable %s is not set", parts[2])
+			}
+			return value
+		default:
+			data, err := os.ReadFile(parts[2])
+			if err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("failed to read %s: %w", parts[2], err)
+			}
+			return strings.TrimSpace(string(data))
+		}
+	})
+	return expanded, firstErr
+}
+
+// isPlaceholder reports whether s consists only of placeholders, so that
+// it is safe to write back out
+func isPlaceholder(s string) bool {
+	return s != "" && placeholderPattern.ReplaceAllString(s, "") == ""
+}
+
+// Provision resolves {env.*} and {file.*} placeholders in the credentials
+// and endpoint, and sets up the API client. It is meant to be called by
+// frameworks such as Caddy once the configuration is loaded; if it is not
+// called, placeholders are resolved on first use and configuration errors
+// are returned by the first API call.
+func (p *Provider) Provision() error {
+	client, err := p.buildClient()
+	if err != nil {
+		return err
+	}
+
+	clientMutex.Lock()
+	p.client = client
+	clientMutex.Unlock()
+	return nil
+}
+
+// providerJSON is Provider without its methods, to avoid recursion
+type providerJSON Provider
+
+// MarshalJSON implements json.Marshaler. The login token and secret key
+// are only written out if they are placeholders; literal secrets are
+// omitted. Durations are
+// written as strings such as "5m0s".
+func (p Provider) MarshalJSON() ([]byte, error) {
+	out := struct {
+		providerJSON
+		RecordCacheTTL     string `json:"record_cache_ttl,omitempty"`
+		DomainCacheTTL     string `json:"domain_cache_ttl,omitempty"`
+		PropagationTimeout string `json:"propagation_timeout,omitempty"`
+		PollInterval       string `json:"poll_interval,omitempty"`
+		HedgeAfter         string `json:"hedge_after,omitempty"`
+		RetryBackoff       string `json:"retry_backoff,omitempty"`
+		CoalesceWindow     string `json:"coalesce_window,omitempty"`
+		IdempotencyWindow  string `json:"idempotency_window,omitempty"`
+	}{
+		providerJSON: providerJSON(p),
+	}
+
+	if !isPlaceholder(out.LoginToken) {
+		out.LoginToken = ""
+	}
+	if !isPlaceholder(out.SecretKey) {
+		out.SecretKey = ""
+	}
+	if p.RecordCacheTTL != 0 {
+		out.RecordCacheTTL = p.RecordCacheTTL.String()
+	}
+	if p.DomainCacheTTL != 0 {
+		out.DomainCacheTTL = p.DomainCacheTTL.String()
+	}
+	if p.PropagationTimeout != 0 {
+		out.PropagationTimeout = p.PropagationTimeout.String()
+	}
+	if p.PollInterval != 0 {
+		out.PollInterval = p.PollInterval.String()
+	}
+	if p.HedgeAfter != 0 {
+		out.HedgeAfter = p.HedgeAfter.String()
+	}
+	if p.RetryBackoff != 0 {
+		out.RetryBackoff = p.RetryBackoff.String()
+	}
+	if p.CoalesceWindow != 0 {
+		out.CoalesceWindow = p.CoalesceWindow.String()
+	}
+	if p.IdempotencyWindow != 0 {
+		out.IdempotencyWindow = p.IdempotencyWindow.String()
+	}
+
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON implements json.Unmarshaler. Durations may be given as
+// strings ("90s", "5m") or as integer nanoseconds.
+func (p *Provider) UnmarshalJSON(data []byte) error {
+	in := struct {
+		*providerJSON
+		RecordCacheTTL     json.RawMessage `json:"record_cache_ttl,omitempty"`
+		DomainCacheTTL     json.RawMessage `json:"domain_cache_ttl,omitempty"`
+		PropagationTimeout json.RawMessage `json:"propagation_timeout,omitempty"`
+		PollInterval       json.RawMessage `json:"poll_interval,omitempty"`
+		HedgeAfter         json.RawMessage `json:"hedge_after,omitempty"`
+		RetryBackoff       json.RawMessage `json:"retry_backoff,omitempty"`
+		CoalesceWindow     json.RawMessage `json:"coalesce_window,omitempty"`
+		IdempotencyWindow  json.RawMessage `json:"idempotency_window,omitempty"`
+	}{
+		providerJSON: (*providerJSON)(p),
+	}
+
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+
+	var err error
+	if p.RecordCacheTTL, err = parseJSONDuration(in.RecordCacheTTL); err != nil {
+		return fmt.Errorf("invalid record_cache_ttl: %w", err)
+	}
+	if p.DomainCacheTTL, err = parseJSONDuration(in.DomainCacheTTL); err != nil {
+		return fmt.Errorf("invalid domain_cache_ttl: %w", err)
+	}
+	if p.PropagationTimeout, err = parseJSONDuration(in.PropagationTimeout); err != nil {
+		return fmt.Errorf("invalid propagation_timeout: %w", err)
+	}
+	if p.PollInterval, err = parseJSONDuration(in.PollInterval); err != nil {
+		return fmt.Errorf("invalid poll_interval: %w", err)
+	}
+	if p.HedgeAfter, err = parseJSONDuration(in.HedgeAfter); err != nil {
+		return fmt.Errorf("invalid hedge_after: %w", err)
+	}
+	if p.RetryBackoff, err = parseJSONDuration(in.RetryBackoff); err != nil {
+		return fmt.Errorf("invalid retry_backoff: %w", err)
+	}
+	if p.CoalesceWindow, err = parseJSONDuration(in.CoalesceWindow); err != nil {
+		return fmt.Errorf("invalid coalesce_window: %w", err)
+	}
+	if p.IdempotencyWindow, err = parseJSONDuration(in.IdempotencyWindow); err != nil {
+		return fmt.Errorf("invalid idempotency_window: %w", err)
+	}
+
+	return nil
+}
+
+// parseJSONDuration accepts a duration string or integer nanoseconds
+func parseJSONDuration(raw json.RawMessage) (time.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return time.ParseDuration(s)
+	}
+
+	var n int64
+	if err := json.Unmarshal(raw, &n); err != nil {
+		return 0, fmt.Errorf("expected a duration string or integer, got %s", raw)
+	}
+	return time.Duration(n), nil
+}
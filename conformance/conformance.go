@@ -0,0 +1,341 @@
+// Package conformance checks that a libdns provider behaves as the libdns
+// interfaces document. It runs against a real zone, so the provider can be
+// this package's, a fork, or a mock backend.
+//
+// Every test record is created under a unique name prefix and removed
+// again when the tests finish; the rest of the zone is not touched.
+package conformance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// Provider is the set of libdns interfaces under test
+type Provider interface {
+	libdns.RecordGetter
+	libdns.RecordAppender
+	libdns.RecordSetter
+	libdns.RecordDeleter
+}
+
+// testTTL is the TTL of the test records
+const testTTL = 10 * time.Minute
+
+// RunConformanceTests runs the conformance tests against zone, which must
+// be a zone the provider can write to, such as "example.com.". It covers
+// GetRecords, AppendRecords, SetRecords and DeleteRecords, including apex
+// and wildcard names, RRsets with several records and the empty-field
+// matching of DeleteRecords.
+func RunConformanceTests(t *testing.T, provider Provider, zone string) {
+	t.Helper()
+
+	s := &suite{
+		provider: provider,
+		zone:     strings.TrimSuffix(zone, ".") + ".",
+		prefix:   fmt.Sprintf("libdns-conformance-%d", time.Now().UnixNano()%1000000),
+	}
+	t.Cleanup(func() { s.cleanup(t) })
+
+	t.Run("GetRecords", s.testGetRecords)
+	t.Run("AppendRecords", s.testAppend)
+	t.Run("AppendRRset", s.testAppendRRset)
+	t.Run("Apex", s.testApex)
+	t.Run("Wildcard", s.testWildcard)
+	t.Run("SetRecordsCreates", s.testSetCreates)
+	t.Run("SetRecordsReplacesRRset", s.testSetReplacesRRset)
+	t.Run("SetRecordsKeepsOtherRRsets", s.testSetKeepsOthers)
+	t.Run("DeleteRecords", s.testDelete)
+	t.Run("DeleteMissingRecords", s.testDeleteMissing)
+	t.Run("DeleteEmptyFields", s.testDeleteEmptyFields)
+}
+
+type suite struct {
+	provider Provider
+	zone     string
+	prefix   string
+
+	// apex holds the apex test records, which cleanup cannot find by prefix
+	apex []libdns.Record
+}
+
+// name returns a test record name relative to the zone
+func (s *suite) name(label string) string {
+	return s.prefix + "-" + label
+}
+
+// ctx returns a context for a single provider call
+func (s *suite) ctx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// rrset returns the records of the zone with the given name and type
+func (s *suite) rrset(t *testing.T, name, typ string) []libdns.RR {
+	t.Helper()
+
+	records, err := s.provider.GetRecords(s.ctx(t), s.zone)
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+
+	var rrs []libdns.RR
+	for _, rec := range records {
+		rr := rec.RR()
+		if s.sameName(rr.Name, name) && strings.EqualFold(rr.Type, typ) {
+			rrs = append(rrs, rr)
+		}
+	}
+	return rrs
+}
+
+// sameName compares record names, which providers may return relative or
+// fully qualified
+func (s *suite) sameName(a, b string) bool {
+	return strings.EqualFold(libdns.RelativeName(libdns.AbsoluteName(a, s.zone), s.zone),
+		libdns.RelativeName(libdns.AbsoluteName(b, s.zone), s.zone))
+}
+
+// values returns the data of records
+func values(rrs []libdns.RR) []string {
+	data := make([]string, len(rrs))
+	for i, rr := range rrs {
+		data[i] = rr.Data
+	}
+	return data
+}
+
+// expectValues fails unless the RRset holds exactly the given values
+func (s *suite) expectValues(t *testing.T, name, typ string, want ...string) {
+	t.Helper()
+
+	got := values(s.rrset(t, name, typ))
+	if len(got) != len(want) {
+		t.Fatalf("%s %s: got values %q, want %q", name, typ, got, want)
+	}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if g == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("%s %s: got values %q, want %q", name, typ, got, want)
+		}
+	}
+}
+
+// txt returns a TXT test record
+func txt(name, text string) libdns.TXT {
+	return libdns.TXT{Name: name, Text: text, TTL: testTTL}
+}
+
+func (s *suite) testGetRecords(t *testing.T) {
+	records, err := s.provider.GetRecords(s.ctx(t), s.zone)
+	if err != nil {
+		t.Fatalf("GetRecords: %v", err)
+	}
+	for _, rec := range records {
+		rr := rec.RR()
+		if rr.Name == "" || rr.Type == "" {
+			t.Errorf("record without name or type: %+v", rr)
+		}
+	}
+}
+
+func (s *suite) testAppend(t *testing.T) {
+	name := s.name("append")
+	added, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "append")})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	if len(added) != 1 {
+		t.Fatalf("AppendRecords returned %d records, want 1", len(added))
+	}
+	if rr := added[0].RR(); !s.sameName(rr.Name, name) || rr.Data != "append" {
+		t.Errorf("AppendRecords returned %+v", rr)
+	}
+
+	s.expectValues(t, name, "TXT", "append")
+}
+
+func (s *suite) testAppendRRset(t *testing.T) {
+	name := s.name("rrset")
+	_, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "one"), txt(name, "two")})
+	if err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	s.expectValues(t, name, "TXT", "one", "two")
+}
+
+func (s *suite) testApex(t *testing.T) {
+	rec := txt("@", s.prefix+"-apex")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{rec}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+	s.apex = append(s.apex, rec)
+
+	found := false
+	for _, rr := range s.rrset(t, "@", "TXT") {
+		if rr.Data == rec.Text {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("apex TXT record %q not found", rec.Text)
+	}
+
+	if _, err := s.provider.DeleteRecords(s.ctx(t), s.zone, []libdns.Record{rec}); err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	s.apex = nil
+
+	for _, rr := range s.rrset(t, "@", "TXT") {
+		if rr.Data == rec.Text {
+			t.Fatalf("apex TXT record %q still present after delete", rec.Text)
+		}
+	}
+}
+
+func (s *suite) testWildcard(t *testing.T) {
+	name := "*." + s.name("wildcard")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "wildcard")}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	s.expectValues(t, name, "TXT", "wildcard")
+}
+
+func (s *suite) testSetCreates(t *testing.T) {
+	name := s.name("set")
+	set, err := s.provider.SetRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "set")})
+	if err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+	if len(set) != 1 {
+		t.Errorf("SetRecords returned %d records, want 1", len(set))
+	}
+
+	s.expectValues(t, name, "TXT", "set")
+}
+
+func (s *suite) testSetReplacesRRset(t *testing.T) {
+	name := s.name("replace")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "old-1"), txt(name, "old-2")}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	if _, err := s.provider.SetRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "new")}); err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+	s.expectValues(t, name, "TXT", "new")
+
+	if _, err := s.provider.SetRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "new-1"), txt(name, "new-2")}); err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+	s.expectValues(t, name, "TXT", "new-1", "new-2")
+}
+
+func (s *suite) testSetKeepsOthers(t *testing.T) {
+	name := s.name("keep")
+	other := s.name("keep-other")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "keep"), txt(other, "other")}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	if _, err := s.provider.SetRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "changed")}); err != nil {
+		t.Fatalf("SetRecords: %v", err)
+	}
+
+	s.expectValues(t, name, "TXT", "changed")
+	s.expectValues(t, other, "TXT", "other")
+}
+
+func (s *suite) testDelete(t *testing.T) {
+	name := s.name("delete")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "keep"), txt(name, "delete")}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	deleted, err := s.provider.DeleteRecords(s.ctx(t), s.zone, []libdns.Record{txt(name, "delete")})
+	if err != nil {
+		t.Fatalf("DeleteRecords: %v", err)
+	}
+	if len(deleted) != 1 {
+		t.Errorf("DeleteRecords returned %d records, want 1", len(deleted))
+	}
+
+	s.expectValues(t, name, "TXT", "keep")
+}
+
+func (s *suite) testDeleteMissing(t *testing.T) {
+	deleted, err := s.provider.DeleteRecords(s.ctx(t), s.zone, []libdns.Record{txt(s.name("missing"), "missing")})
+	if err != nil {
+		t.Fatalf("DeleteRecords of a missing record: %v", err)
+	}
+	if len(deleted) != 0 {
+		t.Errorf("DeleteRecords of a missing record returned %d records, want 0", len(deleted))
+	}
+}
+
+func (s *suite) testDeleteEmptyFields(t *testing.T) {
+	byType := s.name("delete-type")
+	byName := s.name("delete-name")
+	if _, err := s.provider.AppendRecords(s.ctx(t), s.zone, []libdns.Record{
+		txt(byType, "one"), txt(byType, "two"), txt(byName, "one"),
+	}); err != nil {
+		t.Fatalf("AppendRecords: %v", err)
+	}
+
+	// Empty value and TTL match every record of the RRset
+	deleted, err := s.provider.DeleteRecords(s.ctx(t), s.zone, []libdns.Record{libdns.RR{Name: byType, Type: "TXT"}})
+	if err != nil {
+		t.Fatalf("DeleteRecords with empty value: %v", err)
+	}
+	if len(deleted) != 2 {
+		t.Errorf("DeleteRecords with empty value returned %d records, want 2", len(deleted))
+	}
+	s.expectValues(t, byType, "TXT")
+
+	// An empty type matches every record of the name
+	if _, err := s.provider.DeleteRecords(s.ctx(t), s.zone, []libdns.Record{libdns.RR{Name: byName}}); err != nil {
+		t.Fatalf("DeleteRecords with empty type: %v", err)
+	}
+	s.expectValues(t, byName, "TXT")
+}
+
+// cleanup deletes every test record
+func (s *suite) cleanup(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	records, err := s.provider.GetRecords(ctx, s.zone)
+	if err != nil {
+		t.Logf("cleanup: GetRecords: %v", err)
+		return
+	}
+
+	leftovers := s.apex
+	for _, rec := range records {
+		name := libdns.RelativeName(libdns.AbsoluteName(rec.RR().Name, s.zone), s.zone)
+		if strings.Contains(strings.ToLower(name), s.prefix) {
+			leftovers = append(leftovers, rec)
+		}
+	}
+	if len(leftovers) == 0 {
+		return
+	}
+
+	if _, err := s.provider.DeleteRecords(ctx, s.zone, leftovers); err != nil {
+		t.Logf("cleanup: DeleteRecords: %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+package dnspod_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+	"github.com/r6c/dnspodGlobal/conformance"
+)
+
+// TestConformance runs the conformance tests against a real zone. It is
+// skipped unless DNSPOD_TEST_ZONE is set, along with DNSPOD_TOKEN or
+// DNSPOD_SECRET_ID and DNSPOD_SECRET_KEY; DNSPOD_ENDPOINT is optional.
+// Test records are created in the zone and removed again.
+func TestConformance(t *testing.T) {
+	zone := os.Getenv("DNSPOD_TEST_ZONE")
+	if zone == "" {
+		t.Skip("DNSPOD_TEST_ZONE is not set")
+	}
+
+	provider := &dnspod.Provider{
+		LoginToken: os.Getenv("DNSPOD_TOKEN"),
+		SecretID:   os.Getenv("DNSPOD_SECRET_ID"),
+		SecretKey:  os.Getenv("DNSPOD_SECRET_KEY"),
+		Endpoint:   os.Getenv("DNSPOD_ENDPOINT"),
+	}
+	conformance.RunConformanceTests(t, provider, zone)
+}
+
+// TestConformanceFake runs the conformance tests against an in-memory
+// DNSPod backend
+func TestConformanceFake(t *testing.T) {
+	backend := newFakeDNSPod("example.com")
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL}
+	conformance.RunConformanceTests(t, provider, "example.com.")
+}
+
+// fakeDNSPod serves the legacy API actions the provider uses for a single
+// domain. The Batch.* actions are only served if batch is set.
+type fakeDNSPod struct {
+	mutex   sync.Mutex
+	domain  string
+	records []map[string]string
+	nextID  int
+	batch   bool
+	actions []string
+}
+
+func newFakeDNSPod(domain string) *fakeDNSPod {
+	return &fakeDNSPod{
+		domain: domain,
+		records: []map[string]string{
+			{"id": "1", "name": "@", "type": "NS", "value": "f1g1ns1.dnspod.net.", "ttl": "86400", "line": "默认", "line_id": "0", "enabled": "1", "status": "enable"},
+		},
+		nextID: 100,
+	}
+}
+
+// add stores a record on the default line and returns its ID
+func (f *fakeDNSPod) add(name, typ, value, ttl string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.nextID++
+	id := fmt.Sprint(f.nextID)
+	f.records = append(f.records, map[string]string{"id": id, "name": name, "type": typ, "value": value, "ttl": ttl, "line": "默认", "line_id": "0", "enabled": "1", "status": "enable"})
+	return id
+}
+
+// count returns how often an action was called
+func (f *fakeDNSPod) count(action string) int {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	n := 0
+	for _, a := range f.actions {
+		if a == action {
+			n++
+		}
+	}
+	return n
+}
+
+// field returns a field of the record with the given ID
+func (f *fakeDNSPod) field(id, key string) string {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	for _, rec := range f.records {
+		if rec["id"] == id {
+			return rec[key]
+		}
+	}
+	return ""
+}
+
+func (f *fakeDNSPod) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	form := r.PostForm
+	action := strings.TrimPrefix(r.URL.Path, "/")
+	f.actions = append(f.actions, action)
+
+	reply := func(code, message string, fields map[string]any) {
+		out := map[string]any{"status": map[string]string{"code": code, "message": message}}
+		for key, value := range fields {
+			out[key] = value
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(out)
+	}
+	find := func(id string) int {
+		for i, rec := range f.records {
+			if rec["id"] == id {
+				return i
+			}
+		}
+		return -1
+	}
+
+	switch action {
+	case "Info.Version":
+		reply("1", "4.6", nil)
+
+	case "Domain.List":
+		reply("1", "ok", map[string]any{
+			"info":    map[string]any{"domain_total": 1},
+			"domains": []map[string]any{{"id": 1, "name": f.domain, "status": "enable", "grade": "DP_Free"}},
+		})
+
+	case "Record.List":
+		var matches []map[string]string
+		for _, rec := range f.records {
+			if sub := form.Get("sub_domain"); sub != "" && !strings.EqualFold(sub, rec["name"]) {
+				continue
+			}
+			if typ := form.Get("record_type"); typ != "" && !strings.EqualFold(typ, rec["type"]) {
+				continue
+			}
+			matches = append(matches, rec)
+		}
+		reply("1", "ok", map[string]any{
+			"info":    map[string]any{"record_total": fmt.Sprint(len(matches))},
+			"records": matches,
+		})
+
+	case "Record.Create":
+		f.nextID++
+		rec := map[string]string{
+			"id":      fmt.Sprint(f.nextID),
+			"name":    strings.ToLower(form.Get("sub_domain")),
+			"type":    form.Get("record_type"),
+			"value":   form.Get("value"),
+			"ttl":     form.Get("ttl"),
+			"mx":      form.Get("mx"),
+			"line":    form.Get("record_line"),
+			"line_id": "0",
+			"enabled": "1",
+			"status":  "enable",
+		}
+		f.records = append(f.records, rec)
+		reply("1", "ok", map[string]any{"record": map[string]string{"id": rec["id"], "name": rec["name"], "status": "enable"}})
+
+	case "Record.Modify":
+		i := find(form.Get("record_id"))
+		if i < 0 {
+			reply("8", "Record id invalid", nil)
+			return
+		}
+		rec := f.records[i]
+		rec["name"] = strings.ToLower(form.Get("sub_domain"))
+		rec["type"] = form.Get("record_type")
+		rec["value"] = form.Get("value")
+		rec["ttl"] = form.Get("ttl")
+		rec["mx"] = form.Get("mx")
+		if line := form.Get("record_line"); line != "" {
+			rec["line"] = line
+		}
+		reply("1", "ok", map[string]any{"record": map[string]string{"id": rec["id"], "name": rec["name"], "value": rec["value"], "status": "enable"}})
+
+	case "Batch.Detail":
+		// Jobs finish at once, so none are left to look up
+		if !f.batch {
+			http.NotFound(w, r)
+			return
+		}
+		reply("-1", "Job not found", nil)
+
+	case "Batch.Record.Create":
+		if !f.batch {
+			http.NotFound(w, r)
+			return
+		}
+		var records []map[string]string
+		if err := json.Unmarshal([]byte(form.Get("records")), &records); err != nil {
+			reply("-1", err.Error(), nil)
+			return
+		}
+		var results []map[string]string
+		for _, in := range records {
+			f.nextID++
+			rec := map[string]string{
+				"id":      fmt.Sprint(f.nextID),
+				"name":    strings.ToLower(in["sub_domain"]),
+				"type":    in["record_type"],
+				"value":   in["value"],
+				"ttl":     in["ttl"],
+				"mx":      in["mx"],
+				"line":    in["record_line"],
+				"line_id": "0",
+				"enabled": "1",
+				"status":  "enable",
+			}
+			f.records = append(f.records, rec)
+			results = append(results, map[string]string{"id": rec["id"], "sub_domain": rec["name"], "record_type": rec["type"], "value": rec["value"], "status": "ok"})
+		}
+		reply("1", "ok", map[string]any{"detail": []map[string]any{{"records": results}}})
+
+	case "Batch.Record.Modify":
+		if !f.batch {
+			http.NotFound(w, r)
+			return
+		}
+		var results []map[string]string
+		for _, id := range strings.Split(form.Get("record_id"), ",") {
+			i := find(id)
+			if i < 0 {
+				results = append(results, map[string]string{"id": id, "status": "fail", "err_msg": "Record id invalid"})
+				continue
+			}
+			f.records[i][form.Get("change")] = form.Get("change_to")
+			rec := f.records[i]
+			results = append(results, map[string]string{"id": id, "sub_domain": rec["name"], "record_type": rec["type"], "value": rec["value"], "status": "ok"})
+		}
+		reply("1", "ok", map[string]any{"detail": []map[string]any{{"records": results}}})
+
+	case "Record.Remove":
+		i := find(form.Get("record_id"))
+		if i < 0 {
+			reply("8", "Record id invalid", nil)
+			return
+		}
+		f.records = append(f.records[:i], f.records[i+1:]...)
+		reply("1", "ok", nil)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
@@ -0,0 +1,30 @@
+package dnspod
+
+import (
+	"github.com/libdns/libdns"
+)
+
+// ToLibDNSRecord converts a DNSPod record to a libdns record, using the same
+// mapping as the provider. zone is the zone the record belongs to. Unlike
+// the provider in non-strict mode, it does not fall back silently: a
+// record whose TTL, address, MX preference or structured value cannot be
+// parsed yields a *ConversionError.
+func ToLibDNSRecord(raw RawRecord, zone string) (libdns.Record, error) {
+	rec := record(raw)
+	if err := checkOutputConversion(rec, zone); err != nil {
+		return nil, err
+	}
+	return convertToLibDNSRecord(rec, zone), nil
+}
+
+// FromLibDNSRecord converts a libdns record to the DNSPod form the provider
+// would send, with the name made relative to zone. Records that would be
+// changed on the way, such as TTLs that are not whole seconds or that would
+// be replaced by the default, yield a *ConversionError. ID, line and
+// status fields are left empty.
+func FromLibDNSRecord(rec libdns.Record, zone string) (RawRecord, error) {
+	if err := checkInputConversion(rec); err != nil {
+		return RawRecord{}, err
+	}
+	return RawRecord(convertFromLibDNSRecord(rec, zone)), nil
+}
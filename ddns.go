@@ -0,0 +1,347 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultDDNSInterval is how often Run checks the current address
+const defaultDDNSInterval = time.Minute
+
+// DDNSUpdater keeps an address record pointed at a changing IP address,
+// such as the WAN address of a router. A records are updated through
+// Record.Ddns where the endpoint offers it, and AAAA records through
+// Record.Modify.
+//
+// Address changes are debounced: a new address is only pushed once it has
+// been observed for FlapWindow, so a PPPoE reconnect that briefly changes
+// the address and then reverts does not cause two updates, and pushes are
+// at least MinUpdateInterval apart.
+type DDNSUpdater struct {
+	Provider *Provider
+
+	// Zone and Name identify the record; Name is relative to the zone
+	// ("@" for the apex)
+	Zone string
+	Name string
+
+	// Type is "A" (the default) or "AAAA"
+	Type string
+
+	// TTL is used if the record has to be created
+	TTL time.Duration
+
+	// CurrentIP returns the address the record should point at. It is
+	// required by Run.
+	CurrentIP func(ctx context.Context) (netip.Addr, error)
+
+	// Interval is how often Run checks the address. It defaults to one
+	// minute.
+	Interval time.Duration
+
+	// FlapWindow is how long a new address must be observed before it is
+	// pushed. Changes that revert within the window are ignored.
+	FlapWindow time.Duration
+
+	// MinUpdateInterval is the minimum time between two pushes
+	MinUpdateInterval time.Duration
+
+	// StateFile, if set, persists the last pushed address and the record
+	// ID, so that a restarted updater neither pushes the same address
+	// again nor lists the zone to find the record. Changes made to the
+	// record elsewhere are not noticed while the state is in use.
+	StateFile string
+
+	mutex sync.Mutex
+
+	// resolved is set once the record has been looked up
+	resolved bool
+	domainID string
+	current  *record
+
+	// pushed is the address the record points at and lastPush when this
+	// updater last changed it
+	pushed   netip.Addr
+	lastPush time.Time
+
+	// candidate is a new address waiting out the flap window
+	candidate      netip.Addr
+	candidateSince time.Time
+}
+
+// Run checks the address every Interval and updates the record when needed,
+// until ctx is done. Errors are reported through the provider's warning
+// handler and retried on the next check.
+func (u *DDNSUpdater) Run(ctx context.Context) error {
+	if u.CurrentIP == nil {
+		return errors.New("DDNS updater has no CurrentIP source")
+	}
+
+	interval := u.Interval
+	if interval <= 0 {
+		interval = defaultDDNSInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ip, err := u.CurrentIP(ctx)
+		if err != nil {
+			u.Provider.warn(fmt.Errorf("failed to get current IP address: %w", err))
+		} else if _, err := u.Update(ctx, ip); err != nil {
+			u.Provider.warn(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Update observes the current address and pushes it if it has been stable
+// for the flap window and the minimum update interval has passed. It
+// reports whether the record was changed.
+func (u *DDNSUpdater) Update(ctx context.Context, ip netip.Addr) (bool, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if !u.resolved && !u.loadState() {
+		if err := u.resolve(ctx); err != nil {
+			return false, err
+		}
+	}
+
+	now := time.Now()
+	if ip == u.pushed {
+		// Back at the published address, so any pending change flapped
+		u.candidate = netip.Addr{}
+		return false, nil
+	}
+
+	if ip != u.candidate {
+		u.candidate = ip
+		u.candidateSince = now
+	}
+	if now.Sub(u.candidateSince) < u.FlapWindow {
+		return false, nil
+	}
+	if !u.lastPush.IsZero() && now.Sub(u.lastPush) < u.MinUpdateInterval {
+		return false, nil
+	}
+
+	written, err := u.push(ctx, ip)
+	if written {
+		u.pushed = ip
+		u.lastPush = now
+		u.candidate = netip.Addr{}
+
+		if saveErr := u.saveState(); saveErr != nil {
+			u.Provider.warn(saveErr)
+		}
+	}
+	return written, err
+}
+
+// recordType returns the configured record type
+func (u *DDNSUpdater) recordType() string {
+	if u.Type == "" {
+		return "A"
+	}
+	return strings.ToUpper(u.Type)
+}
+
+// recordName returns the record name relative to the zone
+func (u *DDNSUpdater) recordName() string {
+	return extractRecordName(makeAbsoluteName(u.Name, u.Zone), u.Zone)
+}
+
+// resolve looks up the domain and the record's current address
+func (u *DDNSUpdater) resolve(ctx context.Context) error {
+	_, domainID, existingRecords, err := u.Provider.listZoneRecords(ctx, u.Zone)
+	if err != nil {
+		return err
+	}
+
+	u.domainID = domainID
+	u.current = nil
+	u.pushed = netip.Addr{}
+
+	name := u.recordName()
+	for i, rec := range existingRecords {
+		if strings.EqualFold(rec.Name, name) && strings.EqualFold(rec.Type, u.recordType()) {
+			u.current = &existingRecords[i]
+			u.pushed, _ = netip.ParseAddr(rec.Value)
+			break
+		}
+	}
+
+	u.resolved = true
+	return nil
+}
+
+// push points the record at ip, creating it if it does not exist. It
+// reports whether the record was written, which it may have been even if an
+// error is returned.
+func (u *DDNSUpdater) push(ctx context.Context, ip netip.Addr) (bool, error) {
+	p := u.Provider
+	client := p.getClient()
+
+	ctx, unlock, err := p.lockZone(ctx, u.Zone)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	rec := record{
+		Name:  u.recordName(),
+		Type:  u.recordType(),
+		Value: ip.String(),
+	}
+	if u.TTL > 0 {
+		rec.TTL = strconv.Itoa(int(u.TTL.Seconds()))
+	}
+
+	if u.current == nil {
+		created, writeErr := p.createRecord(ctx, client, u.Zone, u.domainID, rec)
+		if created == nil {
+			return false, fmt.Errorf("failed to create record %s: %w", makeAbsoluteName(u.Name, u.Zone), writeErr)
+		}
+		u.current = created
+
+		if err := p.journal(ctx, u.Zone, JournalSet, nil, []libdns.Record{convertToLibDNSRecord(*created, u.Zone)}); err != nil {
+			return true, err
+		}
+		return true, writeErr
+	}
+
+	previous := *u.current
+	rec.Line = previous.Line
+	rec.LineID = previous.LineID
+	rec.TTL = previous.TTL
+
+	var updated *record
+	useDdns := rec.Type == "A" && client.capabilities().Ddns
+	if useDdns {
+		updated, err = client.ddnsRecord(ctx, u.domainID, previous.ID, rec)
+		if errors.Is(err, ErrNotSupported) {
+			p.degrade(client, featureDdns, err)
+			useDdns = false
+		}
+	}
+	if !useDdns {
+		updated, err = client.updateRecord(ctx, u.domainID, previous.ID, rec)
+	}
+	if err != nil {
+		// The record may have been deleted or changed elsewhere, so look
+		// it up again on the next update
+		u.resolved = false
+		return false, fmt.Errorf("failed to update record %s: %w", makeAbsoluteName(u.Name, u.Zone), err)
+	}
+	u.current = updated
+
+	return true, p.journal(ctx, u.Zone, JournalSet,
+		[]libdns.Record{convertToLibDNSRecord(previous, u.Zone)},
+		[]libdns.Record{convertToLibDNSRecord(*updated, u.Zone)})
+}
+
+// ddnsState is the persisted state of a DDNS updater
+type ddnsState struct {
+	Zone     string    `json:"zone"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	DomainID string    `json:"domain_id"`
+	RecordID string    `json:"record_id"`
+	Line     string    `json:"line,omitempty"`
+	TTL      string    `json:"ttl,omitempty"`
+	IP       string    `json:"ip"`
+	Pushed   time.Time `json:"pushed,omitempty"`
+}
+
+// loadState restores the record from the state file. It reports false if
+// there is no usable state for this record.
+func (u *DDNSUpdater) loadState() bool {
+	if u.StateFile == "" {
+		return false
+	}
+
+	data, err := os.ReadFile(u.StateFile)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			u.Provider.warn(fmt.Errorf("failed to read DDNS state: %w", err))
+		}
+		return false
+	}
+
+	var state ddnsState
+	if err := json.Unmarshal(data, &state); err != nil {
+		u.Provider.warn(fmt.Errorf("failed to parse DDNS state %s: %w", u.StateFile, err))
+		return false
+	}
+
+	ip, err := netip.ParseAddr(state.IP)
+	if err != nil || state.RecordID == "" ||
+		!strings.EqualFold(state.Zone, strings.TrimSuffix(u.Zone, ".")) ||
+		!strings.EqualFold(state.Name, u.recordName()) ||
+		state.Type != u.recordType() {
+		return false
+	}
+
+	u.domainID = state.DomainID
+	u.current = &record{
+		ID:    state.RecordID,
+		Name:  state.Name,
+		Type:  state.Type,
+		Value: state.IP,
+		Line:  state.Line,
+		TTL:   state.TTL,
+	}
+	u.pushed = ip
+	u.lastPush = state.Pushed
+	u.resolved = true
+	return true
+}
+
+// saveState writes the current record to the state file, replacing it
+// atomically
+func (u *DDNSUpdater) saveState() error {
+	if u.StateFile == "" || u.current == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(ddnsState{
+		Zone:     strings.TrimSuffix(u.Zone, "."),
+		Name:     u.recordName(),
+		Type:     u.recordType(),
+		DomainID: u.domainID,
+		RecordID: u.current.ID,
+		Line:     u.current.Line,
+		TTL:      u.current.TTL,
+		IP:       u.pushed.String(),
+		Pushed:   u.lastPush,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to encode DDNS state: %w", err)
+	}
+
+	tmp := u.StateFile + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write DDNS state: %w", err)
+	}
+	if err := os.Rename(tmp, u.StateFile); err != nil {
+		return fmt.Errorf("failed to write DDNS state: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// lookupDelegation returns the NS set the parent zone publishes for zone,
+// by asking the parent's authoritative servers directly
+func lookupDelegation(ctx context.Context, zone string) ([]string, error) {
+	zone = dns.Fqdn(strings.ToLower(zone))
+
+	labels := dns.SplitDomainName(zone)
+	if len(labels) < 2 {
+		return nil, fmt.Errorf("zone %s has no parent to delegate from", zone)
+	}
+	parent := dns.Fqdn(strings.Join(labels[1:], "."))
+
+	parentServers, err := net.DefaultResolver.LookupNS(ctx, parent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find nameservers for parent zone %s: %w", parent, err)
+	}
+	if len(parentServers) == 0 {
+		return nil, fmt.Errorf("parent zone %s has no nameservers", parent)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(zone, dns.TypeNS)
+	msg.RecursionDesired = false
+
+	client := new(dns.Client)
+
+	var lastErr error
+	for _, server := range parentServers {
+		resp, _, err := client.ExchangeContext(ctx, msg, net.JoinHostPort(strings.TrimSuffix(server.Host, "."), "53"))
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.Rcode != dns.RcodeSuccess {
+			lastErr = fmt.Errorf("%s answered %s", server.Host, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+
+		// The delegation is a referral in the authority section, or an
+		// answer if the parent server is also authoritative for the zone
+		var nameServers []string
+		for _, rr := range append(resp.Ns, resp.Answer...) {
+			if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, zone) {
+				nameServers = append(nameServers, strings.ToLower(dns.Fqdn(ns.Ns)))
+			}
+		}
+		sort.Strings(nameServers)
+		return nameServers, nil
+	}
+
+	return nil, fmt.Errorf("no parent nameserver answered: %w", lastErr)
+}
@@ -0,0 +1,189 @@
+package dnspod
+
+import (
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// hedgedActions are the idempotent read actions that may be hedged
+var hedgedActions = map[string]bool{
+	"Domain.List": true,
+	"Record.List": true,
+}
+
+// apiEndpoint is one base URL the API can be reached at
+type apiEndpoint struct {
+	url string
+
+	// host overrides the Host header and TLS server name, for mirrors
+	// given as IP addresses
+	host string
+
+	httpClient *http.Client
+}
+
+// addMirrors adds alternative base URLs for the same backend. Mirrors given
+// by IP address are sent the primary host name, so that virtual hosting and
+// certificate verification still work.
+func (c *Client) addMirrors(mirrors []string) error {
+	primary, err := url.Parse(c.baseURL)
+	if err != nil {
+		return fmt.Errorf("invalid endpoint %s: %w", c.baseURL, err)
+	}
+
+	for _, mirror := range mirrors {
+		mirror = strings.TrimSuffix(mirror, "/")
+		u, err := url.Parse(mirror)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid mirror %q", mirror)
+		}
+
+		ep := apiEndpoint{url: mirror, httpClient: c.httpClient}
+		if net.ParseIP(u.Hostname()) != nil {
+			transport := http.DefaultTransport.(*http.Transport).Clone()
+			transport.TLSClientConfig = &tls.Config{ServerName: primary.Hostname()}
+			ep.host = primary.Host
+			ep.httpClient = &http.Client{Timeout: c.httpClient.Timeout, Transport: transport}
+		}
+		c.endpoints = append(c.endpoints, ep)
+	}
+
+	return nil
+}
+
+// post sends a form to an API action once its rate limit allows, starting
+// with the endpoint that last worked and failing over to the next one on
+// connection errors. Those errors mean the request never reached the
+// server, so failing over is safe for writes too.
+func (c *Client) post(ctx context.Context, action, form string) ([]byte, *http.Response, error) {
+	if l := c.limiter(action); l != nil {
+		if err := l.wait(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	start := int(c.active.Load())
+
+	var lastErr error
+	for i := range c.endpoints {
+		index := (start + i) % len(c.endpoints)
+
+		body, resp, err := c.postTo(ctx, c.endpoints[index], action, form)
+		if err != nil && isConnectError(err) && ctx.Err() == nil {
+			lastErr = err
+			continue
+		}
+
+		c.active.Store(int32(index))
+		return body, resp, err
+	}
+
+	return nil, nil, lastErr
+}
+
+// hedgedPost sends a read request like post, and if it has not completed
+// after the hedge delay, sends a second one and takes whichever succeeds
+// first. A failure before the hedge is sent is returned as is.
+func (c *Client) hedgedPost(ctx context.Context, action, form string) ([]byte, *http.Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type result struct {
+		body []byte
+		resp *http.Response
+		err  error
+	}
+	results := make(chan result, 2)
+	send := func() {
+		body, resp, err := c.post(ctx, action, form)
+		results <- result{body, resp, err}
+	}
+
+	go send()
+	inFlight, hedged := 1, false
+
+	timer := time.NewTimer(c.hedgeAfter)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-timer.C:
+			hedged = true
+			inFlight++
+			go send()
+
+		case r := <-results:
+			inFlight--
+			if r.err == nil || !hedged || inFlight == 0 {
+				return r.body, r.resp, r.err
+			}
+		}
+	}
+}
+
+// postTo sends a form to an API action on one endpoint
+func (c *Client) postTo(ctx context.Context, ep apiEndpoint, action, form string) ([]byte, *http.Response, error) {
+	// Create request
+	reqURL := fmt.Sprintf("%s/%s", ep.url, action)
+	req, err := http.NewRequestWithContext(ctx, "POST", reqURL, strings.NewReader(form))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if ep.host != "" {
+		req.Host = ep.host
+	}
+
+	// Set required headers as per DNSPod API specification
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("User-Agent", userAgent)
+
+	// Ask for compressed responses explicitly, so that they are also
+	// compressed on transports that do not do it by default; Record.List
+	// responses of large zones shrink several times
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// Make request
+	resp, err := ep.httpClient.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to make request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Read response
+	var reader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decompress response: %w", err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	return body, resp, nil
+}
+
+// isConnectError reports whether err happened before the request was sent:
+// a failed DNS lookup or TCP connect
+func isConnectError(err error) bool {
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
@@ -0,0 +1,113 @@
+package dnspod
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestPostToGzip(t *testing.T) {
+	const body = `{"status":{"code":"1","message":"ok"}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Accept-Encoding"); got != "gzip" {
+			t.Errorf("Accept-Encoding %q, want gzip", got)
+		}
+		switch r.URL.Path {
+		case "/Plain":
+			w.Write([]byte(body))
+		case "/Gzip":
+			var buf bytes.Buffer
+			gz := gzip.NewWriter(&buf)
+			gz.Write([]byte(body))
+			gz.Close()
+			w.Header().Set("Content-Encoding", "GZIP")
+			w.Write(buf.Bytes())
+		case "/Corrupt":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte(body))
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	c := newClient("1,token", server.URL)
+	ctx := context.Background()
+
+	for _, action := range []string{"Plain", "Gzip"} {
+		got, _, err := c.postTo(ctx, c.endpoints[0], action, "")
+		if err != nil {
+			t.Errorf("%s: %v", action, err)
+		} else if string(got) != body {
+			t.Errorf("%s: body %q, want %q", action, got, body)
+		}
+	}
+	if _, _, err := c.postTo(ctx, c.endpoints[0], "Corrupt", ""); err == nil {
+		t.Error("Corrupt: no error for a body that is not gzip")
+	}
+}
+
+func TestPostFailover(t *testing.T) {
+	// dead refuses connections
+	dead := httptest.NewServer(http.NotFoundHandler())
+	dead.Close()
+
+	var mutex sync.Mutex
+	var served []string
+	serve := func(name string) *httptest.Server {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mutex.Lock()
+			served = append(served, name)
+			mutex.Unlock()
+			w.Write([]byte(name))
+		}))
+		// Every request dials, so that a closed server refuses it
+		server.Config.SetKeepAlivesEnabled(false)
+		return server
+	}
+	first, second := serve("first"), serve("second")
+	t.Cleanup(second.Close)
+
+	c := newClient("1,token", dead.URL)
+	if err := c.addMirrors([]string{first.URL, second.URL}); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	post := func(want string, wantActive int32) {
+		t.Helper()
+		body, _, err := c.post(ctx, "Info.Version", "")
+		if err != nil {
+			t.Fatalf("post: %v", err)
+		}
+		if string(body) != want {
+			t.Errorf("served by %q, want %q", body, want)
+		}
+		if active := c.active.Load(); active != wantActive {
+			t.Errorf("active endpoint %d, want %d", active, wantActive)
+		}
+	}
+
+	// The primary is down, so the mirrors are tried in order, and the one
+	// that worked is tried first next time
+	post("first", 1)
+	post("first", 1)
+
+	// Once it goes down too, the next one takes over
+	first.Close()
+	post("second", 2)
+	post("second", 2)
+
+	if len(served) != 4 {
+		t.Errorf("served %v, want 4 requests", served)
+	}
+
+	// With every endpoint down, the last connection error is returned
+	second.Close()
+	if _, _, err := c.post(ctx, "Info.Version", ""); err == nil || !isConnectError(err) {
+		t.Errorf("got %v, want a connection error", err)
+	}
+}
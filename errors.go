@@ -0,0 +1,109 @@
+package dnspod
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrZoneNotFound is matched by errors for zones that are not in the DNSPod
+// account, or that are inactive while SkipInactiveZones is set
+var ErrZoneNotFound = errors.New("zone not found")
+
+// ErrNoRecords is returned by GetRecords and GetRawRecords when
+// ErrorOnEmpty is set and the zone has no records to return
+var ErrNoRecords = errors.New("zone has no records")
+
+// ErrNotSupported is matched by API errors for actions or features that the
+// endpoint or the account's plan does not offer
+var ErrNotSupported = errors.New("not supported by the API endpoint or plan")
+
+// InactiveZoneError is returned for paused, locked or spam-flagged zones
+// when SkipInactiveZones is set
+type InactiveZoneError struct {
+	Zone   string
+	Status string
+}
+
+func (e *InactiveZoneError) Error() string {
+	return fmt.Sprintf("domain %s is not active in DNSPod (status %s)", e.Zone, e.Status)
+}
+
+// Unwrap makes InactiveZoneError match ErrZoneNotFound
+func (e *InactiveZoneError) Unwrap() error {
+	return ErrZoneNotFound
+}
+
+// APIError is returned when the DNSPod API answers with an error status
+type APIError struct {
+	// Action is the API action that failed, such as "Record.Create"
+	Action string
+
+	// Code and Message are the status of the response. With API keys,
+	// Code is the API 3.0 error code, such as
+	// "InvalidParameter.DomainInvalid", except for frequency limits.
+	Code    string
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %s - %s", e.Code, e.Message)
+}
+
+// Is makes errors whose message says that a feature is unavailable match
+// ErrNotSupported
+func (e *APIError) Is(target error) bool {
+	return target == ErrNotSupported && isNotSupportedMessage(e.Message)
+}
+
+// HTTPError is returned for API responses with an HTTP status other than
+// 200 OK
+type HTTPError struct {
+	StatusCode int
+	Status     string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error: %s", e.Status)
+}
+
+// Is makes 404 responses, which the API gives for unknown actions, match
+// ErrNotSupported
+func (e *HTTPError) Is(target error) bool {
+	return target == ErrNotSupported && e.StatusCode == http.StatusNotFound
+}
+
+// notFoundCodes are the status codes of API errors for domain and record
+// IDs that do not exist
+var notFoundCodes = map[string]bool{
+	"6": true, // 域名ID错误
+	"8": true, // 记录ID错误
+
+	"InvalidParameter.DomainIdInvalid": true,
+	"InvalidParameter.RecordIdInvalid": true,
+}
+
+// IsRateLimited reports whether err is an API error for exceeding the
+// account's request limit
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.Code == frequencyLimitCode {
+		return true
+	}
+	var httpErr *HTTPError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusTooManyRequests
+}
+
+// IsNotFound reports whether err says that a zone or record does not
+// exist: ErrZoneNotFound, or an API error for an unknown domain or record
+func IsNotFound(err error) bool {
+	if errors.Is(err, ErrZoneNotFound) {
+		return true
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	return notFoundCodes[apiErr.Code] || strings.HasPrefix(apiErr.Code, "ResourceNotFound")
+}
@@ -0,0 +1,32 @@
+package dnspod
+
+import (
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// GenericRecord is a record returned with its data as stored by DNSPod:
+// one of a type without a specific struct, such as SPF or URL forwarding,
+// or one whose data does not parse as its type. Unlike a plain libdns.RR,
+// it carries the record's RecordMetadata, so that its ID, line and weight
+// survive a round trip.
+type GenericRecord struct {
+	Name string
+	TTL  time.Duration
+	Type string
+	Data string
+
+	// Optional custom data associated with the provider serving this record.
+	ProviderData any
+}
+
+// RR implements libdns.Record
+func (r GenericRecord) RR() libdns.RR {
+	return libdns.RR{
+		Name: r.Name,
+		TTL:  r.TTL,
+		Type: r.Type,
+		Data: r.Data,
+	}
+}
@@ -1,8 +1,19 @@
 module github.com/r6c/dnspodGlobal
 
-go 1.24
+go 1.24.0
 
-require github.com/libdns/libdns v1.1.0
+require (
+	github.com/libdns/libdns v1.1.0
+	github.com/miekg/dns v1.1.72
+)
+
+require (
+	golang.org/x/mod v0.31.0 // indirect
+	golang.org/x/net v0.48.0 // indirect
+	golang.org/x/sync v0.19.0 // indirect
+	golang.org/x/sys v0.39.0 // indirect
+	golang.org/x/tools v0.40.0 // indirect
+)
 
 // Replace the old dnspodGlobal-go dependency with a direct implementation
 // require github.com/r6c/dnspodGlobal-go v0.4.1-0.20220630051153-28f3af61ea62
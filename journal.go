@@ -0,0 +1,386 @@
+package dnspod
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// JournalOp identifies the kind of mutation recorded in a journal entry
+type JournalOp string
+
+const (
+	JournalAppend JournalOp = "append"
+	JournalSet    JournalOp = "set"
+	JournalDelete JournalOp = "delete"
+)
+
+// JournalEntry records a single mutation together with the record state
+// before and after it was applied
+type JournalEntry struct {
+	Seq    uint64          `json:"seq"`
+	Time   time.Time       `json:"time"`
+	Zone   string          `json:"zone"`
+	Op     JournalOp       `json:"op"`
+	Before []JournalRecord `json:"before,omitempty"`
+	After  []JournalRecord `json:"after,omitempty"`
+}
+
+// JournalRecord is a record as stored in a journal entry. Metadata holds
+// its DNSPod ID, line and weight, so that replaying or inverting the entry
+// acts on that record rather than another one with the same data; it is
+// nil for records that had none.
+type JournalRecord struct {
+	libdns.RR
+	Metadata *RecordMetadata `json:"metadata,omitempty"`
+}
+
+// JournalStore persists journal entries. Implementations must be safe for
+// concurrent use.
+type JournalStore interface {
+	// Append stores an entry. Entries are appended in sequence order.
+	Append(ctx context.Context, entry JournalEntry) error
+
+	// Range returns the entries with from <= Seq <= to, in sequence order.
+	Range(ctx context.Context, from, to uint64) ([]JournalEntry, error)
+
+	// LastSeq returns the highest stored sequence number, or 0 if empty.
+	LastSeq(ctx context.Context) (uint64, error)
+}
+
+// Journal records every mutation made through a Provider and supports
+// replaying or inverting ranges of entries. It is intended for zones that
+// are managed exclusively by this provider; changes made elsewhere are not
+// tracked and may make an inversion inaccurate.
+type Journal struct {
+	Store JournalStore
+
+	mutex sync.Mutex
+	seq   uint64
+}
+
+// NewJournal creates a journal backed by the given store
+func NewJournal(store JournalStore) *Journal {
+	return &Journal{Store: store}
+}
+
+// record assigns the next sequence number to an entry and stores it
+func (j *Journal) record(ctx context.Context, zone string, op JournalOp, before, after []libdns.Record) error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+
+	if j.seq == 0 {
+		last, err := j.Store.LastSeq(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to read journal position: %w", err)
+		}
+		j.seq = last
+	}
+
+	entry := JournalEntry{
+		Seq:    j.seq + 1,
+		Time:   time.Now(),
+		Zone:   zone,
+		Op:     op,
+		Before: toJournal(before),
+		After:  toJournal(after),
+	}
+
+	if err := j.Store.Append(ctx, entry); err != nil {
+		return fmt.Errorf("failed to append journal entry: %w", err)
+	}
+	j.seq = entry.Seq
+
+	return nil
+}
+
+// Entries returns the journal entries with from <= Seq <= to
+func (j *Journal) Entries(ctx context.Context, from, to uint64) ([]JournalEntry, error) {
+	return j.Store.Range(ctx, from, to)
+}
+
+// Replay re-applies the entries with from <= Seq <= to against the provider,
+// in their original order. This acts as "redo" after an Invert.
+func (j *Journal) Replay(ctx context.Context, p *Provider, from, to uint64) error {
+	entries, err := j.Store.Range(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	for _, entry := range entries {
+		if err := p.applyJournal(ctx, entry, entry.Before, entry.After); err != nil {
+			return fmt.Errorf("failed to replay journal entry %d: %w", entry.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// Invert undoes the entries with from <= Seq <= to against the provider,
+// newest first. Undoing a set restores the previous record; if there was
+// none, the record that was created is deleted instead.
+func (j *Journal) Invert(ctx context.Context, p *Provider, from, to uint64) error {
+	entries, err := j.Store.Range(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if err := p.applyJournal(ctx, entry, entry.After, entry.Before); err != nil {
+			return fmt.Errorf("failed to invert journal entry %d: %w", entry.Seq, err)
+		}
+	}
+
+	return nil
+}
+
+// applyJournal turns the records of an entry in state from into those in
+// state to, record by record: records in from are updated to the record
+// at the same position in to, or deleted if to is empty, and without from
+// the records in to are created. Records in from that no longer exist are
+// created again if needed, or skipped for deletes. Other records of the
+// same RRsets are left alone.
+func (p *Provider) applyJournal(ctx context.Context, entry JournalEntry, from, to []JournalRecord) error {
+	switch entry.Op {
+	case JournalAppend, JournalSet, JournalDelete:
+	default:
+		return fmt.Errorf("unknown journal operation %q", entry.Op)
+	}
+
+	zone := entry.Zone
+	client := p.getClient()
+	domainID, err := client.getDomainID(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
+	}
+
+	oldRecords, newRecords := fromJournal(zone, from), fromJournal(zone, to)
+	plan := &Plan{Zone: zone}
+	for i, libRec := range oldRecords {
+		existing, err := p.journalTarget(ctx, client, domainID, zone, libRec)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case i >= len(newRecords) && existing == nil:
+		case i >= len(newRecords):
+			plan.Changes = append(plan.Changes, Change{Op: ChangeDelete, Before: convertToLibDNSRecord(*existing, zone), existing: existing})
+		case existing == nil:
+			plan.Changes = append(plan.Changes, Change{Op: ChangeCreate, After: newRecords[i]})
+		default:
+			plan.Changes = append(plan.Changes, Change{Op: ChangeUpdate, Before: convertToLibDNSRecord(*existing, zone), After: newRecords[i], existing: existing})
+		}
+	}
+	for i := len(oldRecords); i < len(newRecords); i++ {
+		plan.Changes = append(plan.Changes, Change{Op: ChangeCreate, After: newRecords[i]})
+	}
+
+	_, err = p.applyPlan(ctx, plan)
+	return err
+}
+
+// journalTarget finds the record a journaled record refers to: the record
+// with its ID, or else the first with the same name, type, value and line.
+// It returns nil if there is none.
+func (p *Provider) journalTarget(ctx context.Context, client *Client, domainID, zone string, libRec libdns.Record) (*record, error) {
+	want := convertFromLibDNSRecord(libRec, zone)
+	candidates, err := client.findRecords(ctx, domainID, want.Name, want.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find record %s: %w", libRec.RR().Name, err)
+	}
+
+	if meta, ok := recordMetadata(libRec); ok && meta.ID != "" {
+		for i, have := range candidates {
+			if have.ID == meta.ID {
+				return &candidates[i], nil
+			}
+		}
+	}
+	for i, have := range candidates {
+		if !isSystemRecord(have) &&
+			sameRecordValue(want.Type, have.Value, want.Value) &&
+			(!usesMX(want.Type) || have.MX == want.MX) &&
+			client.sameLine(want, have) {
+			return &candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// MemoryJournalStore keeps journal entries in memory
+type MemoryJournalStore struct {
+	mutex   sync.RWMutex
+	entries []JournalEntry
+}
+
+// Append implements JournalStore
+func (s *MemoryJournalStore) Append(ctx context.Context, entry JournalEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.entries = append(s.entries, entry)
+	return nil
+}
+
+// Range implements JournalStore
+func (s *MemoryJournalStore) Range(ctx context.Context, from, to uint64) ([]JournalEntry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	var entries []JournalEntry
+	for _, entry := range s.entries {
+		if entry.Seq >= from && entry.Seq <= to {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// LastSeq implements JournalStore
+func (s *MemoryJournalStore) LastSeq(ctx context.Context) (uint64, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if len(s.entries) == 0 {
+		return 0, nil
+	}
+	return s.entries[len(s.entries)-1].Seq, nil
+}
+
+// FileJournalStore appends journal entries to a file as JSON lines
+type FileJournalStore struct {
+	Path string
+
+	mutex sync.Mutex
+}
+
+// Append implements JournalStore
+func (s *FileJournalStore) Append(ctx context.Context, entry JournalEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal entry: %w", err)
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open journal file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write journal file: %w", err)
+	}
+
+	return f.Sync()
+}
+
+// Range implements JournalStore
+func (s *FileJournalStore) Range(ctx context.Context, from, to uint64) ([]JournalEntry, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var entries []JournalEntry
+	err := s.scan(func(entry JournalEntry) {
+		if entry.Seq >= from && entry.Seq <= to {
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+// LastSeq implements JournalStore
+func (s *FileJournalStore) LastSeq(ctx context.Context) (uint64, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var last uint64
+	err := s.scan(func(entry JournalEntry) {
+		last = entry.Seq
+	})
+	return last, err
+}
+
+// scan calls fn for every entry in the journal file
+func (s *FileJournalStore) scan(fn func(JournalEntry)) error {
+	f, err := os.Open(s.Path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open journal file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("failed to parse journal file: %w", err)
+		}
+		fn(entry)
+	}
+
+	return scanner.Err()
+}
+
+// toJournal reduces records to their RR form and metadata for storage
+func toJournal(records []libdns.Record) []JournalRecord {
+	if len(records) == 0 {
+		return nil
+	}
+	out := make([]JournalRecord, len(records))
+	for i, rec := range records {
+		out[i].RR = rec.RR()
+		if meta, ok := recordMetadata(rec); ok {
+			out[i].Metadata = &meta
+		}
+	}
+	return out
+}
+
+// toRRs reduces records to their RR form
+func toRRs(records []libdns.Record) []libdns.RR {
+	if len(records) == 0 {
+		return nil
+	}
+	rrs := make([]libdns.RR, len(records))
+	for i, rec := range records {
+		rrs[i] = rec.RR()
+	}
+	return rrs
+}
+
+// fromJournal converts stored records back into records, with their
+// metadata attached
+func fromJournal(zone string, stored []JournalRecord) []libdns.Record {
+	records := make([]libdns.Record, len(stored))
+	for i, jr := range stored {
+		records[i] = jr.RR
+		if jr.Metadata != nil {
+			typed := convertToLibDNSRecord(convertFromLibDNSRecord(jr.RR, zone), zone)
+			records[i] = withMetadata(typed, *jr.Metadata)
+		}
+	}
+	return records
+}
+
+// Interface guards
+var (
+	_ JournalStore = (*MemoryJournalStore)(nil)
+	_ JournalStore = (*FileJournalStore)(nil)
+)
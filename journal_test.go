@@ -0,0 +1,93 @@
+package dnspod_test
+
+import (
+	"context"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+// txtRecords lists the TXT records of the fake backend as "line:value"
+func txtRecords(backend *fakeDNSPod) string {
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	var out []string
+	for _, rec := range backend.records {
+		if rec["type"] == "TXT" {
+			out = append(out, rec["line"]+":"+rec["value"])
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+func TestJournalKeepsLines(t *testing.T) {
+	backend := newFakeDNSPod("example.com")
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	journal := dnspod.NewJournal(&dnspod.MemoryJournalStore{})
+	provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL, Journal: journal}
+	ctx := context.Background()
+	const zone = "example.com."
+
+	telecom := dnspod.RecordMetadata{Line: "电信"}
+	_, err := provider.AppendRecords(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a"},
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "c"},
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a", ProviderData: telecom},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	const initial = "电信:a 默认:a 默认:c"
+	if got := txtRecords(backend); got != initial {
+		t.Fatalf("records %q, want %q", got, initial)
+	}
+	start, err := journal.Store.LastSeq(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Change the telecom record, and one of the two default-line records
+	if _, err := provider.SetRecords(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "b", ProviderData: telecom},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := provider.SetRecords(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a"},
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "x"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	const changed = "电信:b 默认:a 默认:x"
+	if got := txtRecords(backend); got != changed {
+		t.Fatalf("records %q, want %q", got, changed)
+	}
+	end, err := journal.Store.LastSeq(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := journal.Invert(ctx, provider, start+1, end); err != nil {
+		t.Fatal(err)
+	}
+	if got := txtRecords(backend); got != initial {
+		t.Errorf("records after invert %q, want %q", got, initial)
+	}
+
+	if err := journal.Replay(ctx, provider, start+1, end); err != nil {
+		t.Fatal(err)
+	}
+	if got := txtRecords(backend); got != changed {
+		t.Errorf("records after replay %q, want %q", got, changed)
+	}
+}
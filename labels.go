@@ -0,0 +1,84 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ParseLabels parses a structured remark, "key=value;key2=value2", into
+// labels. It reports false if the remark is empty or free text.
+func ParseLabels(remark string) (map[string]string, bool) {
+	labels := make(map[string]string)
+	for _, pair := range strings.Split(remark, ";") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, false
+		}
+		labels[key] = strings.TrimSpace(value)
+	}
+
+	if len(labels) == 0 {
+		return nil, false
+	}
+	return labels, true
+}
+
+// FormatLabels serializes labels as a structured remark, sorted by key
+func FormatLabels(labels map[string]string) (string, error) {
+	keys := make([]string, 0, len(labels))
+	for key, value := range labels {
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "=;") {
+			return "", fmt.Errorf("invalid label key %q", key)
+		}
+		if strings.Contains(value, ";") {
+			return "", fmt.Errorf("invalid value %q of label %s: must not contain ';'", value, key)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + labels[key]
+	}
+	return strings.Join(pairs, ";"), nil
+}
+
+// inputRemark returns the remark to write for a record: its labels if it
+// has any, its remark otherwise. Both come from the record's
+// RecordMetadata; records without one get no remark.
+func inputRemark(libRec libdns.Record) (string, error) {
+	meta, ok := recordMetadata(libRec)
+	if !ok {
+		return "", nil
+	}
+	if len(meta.Labels) > 0 {
+		return FormatLabels(meta.Labels)
+	}
+	return meta.Remark, nil
+}
+
+// setRemark sets the remark of a record with Record.Remark
+func (c *Client) setRemark(ctx context.Context, domainID, recordID, remark string) error {
+	params := map[string]string{
+		"domain_id": domainID,
+		"record_id": recordID,
+		"remark":    remark,
+	}
+
+	_, err := c.makeRequest(ctx, "Record.Remark", params)
+	c.invalidateRecords(domainID)
+	if err != nil {
+		return fmt.Errorf("failed to set record remark: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package dnspod
+
+import "strings"
+
+const (
+	// defaultLineCN is the default line of dnsapi.cn
+	defaultLineCN = "默认"
+
+	// defaultLineIntl is the default line of the international API
+	defaultLineIntl = "default"
+)
+
+// lineNames maps the Chinese line names used by dnsapi.cn to the English
+// names used by the international API
+var lineNames = map[string]string{
+	"默认":   "default",
+	"境外":   "oversea",
+	"电信":   "telecom",
+	"联通":   "unicom",
+	"移动":   "mobile",
+	"教育网":  "edu",
+	"搜索引擎": "search",
+}
+
+// englishLineNames is the reverse of lineNames
+var englishLineNames = func() map[string]string {
+	m := make(map[string]string, len(lineNames))
+	for cn, en := range lineNames {
+		m[en] = cn
+	}
+	return m
+}()
+
+// LocalizeLine translates a line name to the language of an endpoint, so
+// that configurations written for either API work with both: "默认" becomes
+// "default" for https://api.dnspod.com and "default" becomes "默认" for
+// https://dnsapi.cn. English names are matched case-insensitively and
+// returned in lower case, so "Default" becomes "default" too. Names
+// without a known translation, such as custom lines, are returned as is.
+func LocalizeLine(line, endpoint string) string {
+	if strings.TrimSuffix(endpoint, "/") == intlBaseURL {
+		if en, ok := lineNames[line]; ok {
+			return en
+		}
+		if _, ok := englishLineNames[strings.ToLower(line)]; ok {
+			return strings.ToLower(line)
+		}
+		return line
+	}
+
+	if cn, ok := englishLineNames[strings.ToLower(line)]; ok {
+		return cn
+	}
+	return line
+}
+
+// lineParams sets the line parameters of a record write. Aliases are
+// resolved first, then the name is localized for the client's endpoint.
+// If the line's ID is known, it is sent too, which DNSPod prefers over the
+// name. Records keep their current line when it is known and use the
+// client's default line otherwise.
+func (c *Client) lineParams(params map[string]string, rec record) {
+	line := rec.Line
+	if line == "" {
+		line = c.defaultLine
+	}
+	if alias, ok := c.lineAliases[line]; ok {
+		line = alias
+	}
+	params["record_line"] = LocalizeLine(line, c.baseURL)
+
+	switch {
+	case c.lineIDs[line] != "":
+		params["record_line_id"] = c.lineIDs[line]
+	case rec.LineID != "" && rec.Line != "":
+		params["record_line_id"] = rec.LineID
+	}
+}
+
+// inputRRset returns the RRset a record is written to, with the line it is
+// sent with. Line names are compared case-insensitively, as DNSPod does.
+func (c *Client) inputRRset(rec record) rrsetKey {
+	params := make(map[string]string)
+	c.lineParams(params, rec)
+	return rrsetKey{name: strings.ToLower(rec.Name), typ: strings.ToUpper(rec.Type), line: strings.ToLower(params["record_line"])}
+}
+
+// storedRRset returns the RRset of an existing record
+func (c *Client) storedRRset(rec record) rrsetKey {
+	line := LocalizeLine(rec.Line, c.baseURL)
+	return rrsetKey{name: strings.ToLower(rec.Name), typ: strings.ToUpper(rec.Type), line: strings.ToLower(line)}
+}
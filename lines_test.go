@@ -0,0 +1,117 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestLocalizeLine(t *testing.T) {
+	tests := []struct {
+		line, endpoint, want string
+	}{
+		{"默认", intlBaseURL, "default"},
+		{"default", intlBaseURL, "default"},
+		{"Default", intlBaseURL, "default"},
+		{"Telecom", intlBaseURL + "/", "telecom"},
+		{"Custom", intlBaseURL, "Custom"},
+		{"Default", baseURL, "默认"},
+		{"电信", baseURL, "电信"},
+		{"Custom", "", "Custom"},
+	}
+	for _, tt := range tests {
+		if got := LocalizeLine(tt.line, tt.endpoint); got != tt.want {
+			t.Errorf("LocalizeLine(%q, %q) = %q, want %q", tt.line, tt.endpoint, got, tt.want)
+		}
+	}
+}
+
+// redirectTransport sends every request to a test server
+type redirectTransport struct {
+	target *url.URL
+}
+
+func (rt redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = rt.target.Scheme
+	req.URL.Host = rt.target.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestIntlLineMatching(t *testing.T) {
+	var (
+		mutex   sync.Mutex
+		actions []string
+		value   = "old"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		r.ParseForm()
+		action := strings.TrimPrefix(r.URL.Path, "/")
+		actions = append(actions, action)
+		out := map[string]any{"status": map[string]string{"code": "1", "message": "ok"}}
+		switch action {
+		case "Domain.List":
+			out["info"] = map[string]any{"domain_total": 1}
+			out["domains"] = []map[string]any{{"id": 1, "name": "example.com", "status": "enable"}}
+		case "Record.List":
+			var records []map[string]string
+			if value != "" {
+				records = append(records, map[string]string{"id": "7", "name": "test", "type": "TXT", "value": value, "ttl": "600", "line": "Default", "line_id": "0", "enabled": "1", "status": "enable"})
+			}
+			out["info"] = map[string]any{"record_total": len(records)}
+			out["records"] = records
+		case "Record.Info":
+			out["record"] = map[string]string{"id": "7", "sub_domain": "test", "record_type": "TXT", "record_line": "Default", "record_line_id": "0", "value": value, "ttl": "600", "enabled": "1"}
+		case "Record.Modify":
+			if line := r.PostForm.Get("record_line"); line != "default" {
+				t.Errorf("modified with line %q, want default", line)
+			}
+			value = r.PostForm.Get("value")
+			out["record"] = map[string]string{"id": "7", "name": "test", "value": value, "status": "enable"}
+		case "Record.Remove":
+			value = ""
+		default:
+			t.Errorf("unexpected action %s", action)
+		}
+		json.NewEncoder(w).Encode(out)
+	}))
+	t.Cleanup(server.Close)
+
+	p := &Provider{LoginToken: "1,token", Endpoint: intlBaseURL, Verify: true}
+	target, _ := url.Parse(server.URL)
+	p.getClient().httpClient.Transport = redirectTransport{target: target}
+
+	ctx := context.Background()
+	rec := libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "new"}
+	if _, err := p.SetRecords(ctx, "example.com.", []libdns.Record{rec}); err != nil {
+		t.Fatal(err)
+	}
+	if value != "new" {
+		t.Errorf("stored value %q, want new", value)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{rec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deleted) != 1 || value != "" {
+		t.Errorf("deleted %d records, stored value %q", len(deleted), value)
+	}
+
+	for _, action := range actions {
+		if action == "Record.Create" {
+			t.Errorf("created a duplicate record: %v", actions)
+		}
+	}
+}
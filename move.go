@@ -0,0 +1,204 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MoveOptions configures MoveRecords
+type MoveOptions struct {
+	// Types restricts the move to the given record types
+	Types []string
+
+	// KeepOriginals copies the records without deleting them from the old name
+	KeepOriginals bool
+
+	// DryRun returns the planned changes without executing them
+	DryRun bool
+}
+
+// PlanMoveRecords plans copying every record at oldName to newName and,
+// unless KeepOriginals is set, deleting the originals. Names may be
+// absolute or relative to the zone.
+func (p *Provider) PlanMoveRecords(ctx context.Context, zone, oldName, newName string, opts MoveOptions) (*Plan, error) {
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	from := extractRecordName(makeAbsoluteName(oldName, zone), zone)
+	to := extractRecordName(makeAbsoluteName(newName, zone), zone)
+	if strings.EqualFold(from, to) {
+		return nil, fmt.Errorf("cannot move records of %s onto themselves", makeAbsoluteName(oldName, zone))
+	}
+
+	filter := RecordFilter{Names: []string{oldName}, Types: opts.Types}
+	plan := &Plan{Zone: zone}
+
+	var deletes []Change
+	for _, existingRec := range existingRecords {
+		if isSystemRecord(existingRec) || !filter.matches(existingRec, zone) {
+			continue
+		}
+
+		moved := existingRec
+		moved.Name = to
+
+		plan.Changes = append(plan.Changes, Change{
+			Op:    ChangeCreate,
+			After: convertToLibDNSRecord(moved, zone),
+		})
+
+		if !opts.KeepOriginals {
+			existing := existingRec
+			deletes = append(deletes, Change{
+				Op:       ChangeDelete,
+				Before:   convertToLibDNSRecord(existingRec, zone),
+				existing: &existing,
+			})
+		}
+	}
+
+	// Create all copies before deleting anything, so a failure part way
+	// through never leaves the name without records
+	plan.Changes = append(plan.Changes, deletes...)
+
+	return plan, nil
+}
+
+// MoveRecords copies all records from oldName to newName and, unless
+// KeepOriginals is set, deletes the originals. If any step fails, the
+// changes made so far are rolled back. It returns the applied changes, or
+// the planned changes if DryRun is set.
+func (p *Provider) MoveRecords(ctx context.Context, zone, oldName, newName string, opts MoveOptions) ([]Change, error) {
+	plan, err := p.PlanMoveRecords(ctx, zone, oldName, newName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return plan.Changes, nil
+	}
+
+	applied, err := p.ApplyPlan(ctx, plan)
+	if err != nil {
+		if rbErr := p.rollback(ctx, zone, applied); rbErr != nil {
+			return applied, fmt.Errorf("%w (rollback also failed: %v)", err, rbErr)
+		}
+		return nil, fmt.Errorf("moving records was rolled back: %w", err)
+	}
+
+	return applied, nil
+}
+
+// CopyOptions configures CopyRecords
+type CopyOptions struct {
+	// DestZone is the zone to copy into. It defaults to the source zone.
+	DestZone string
+
+	// IncludeSubdomains also copies records below the source name, keeping
+	// their position relative to it (a.staging -> a.staging2).
+	IncludeSubdomains bool
+
+	// Filter further restricts which source records are copied
+	Filter RecordFilter
+
+	// DryRun returns the planned changes without executing them
+	DryRun bool
+}
+
+// PlanCopyRecords plans creating copies of the records at fromName (and
+// optionally below it) under toName, in the same or another zone. Record
+// values are copied verbatim; targets that point into the source zone are
+// not rewritten.
+func (p *Provider) PlanCopyRecords(ctx context.Context, zone, fromName, toName string, opts CopyOptions) (*Plan, error) {
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	destZone := opts.DestZone
+	if destZone == "" {
+		destZone = zone
+	}
+
+	from := extractRecordName(makeAbsoluteName(fromName, zone), zone)
+	to := extractRecordName(makeAbsoluteName(toName, destZone), destZone)
+	if strings.EqualFold(from, to) && strings.EqualFold(strings.TrimSuffix(zone, "."), strings.TrimSuffix(destZone, ".")) {
+		return nil, fmt.Errorf("cannot copy records of %s onto themselves", makeAbsoluteName(fromName, zone))
+	}
+
+	plan := &Plan{Zone: destZone}
+	for _, existingRec := range existingRecords {
+		if isSystemRecord(existingRec) || !opts.Filter.matches(existingRec, zone) {
+			continue
+		}
+
+		name, ok := renameRecord(existingRec.Name, from, to, opts.IncludeSubdomains)
+		if !ok {
+			continue
+		}
+
+		copied := existingRec
+		copied.Name = name
+
+		plan.Changes = append(plan.Changes, Change{
+			Op:    ChangeCreate,
+			After: convertToLibDNSRecord(copied, destZone),
+		})
+	}
+
+	return plan, nil
+}
+
+// CopyRecords copies the records at fromName to toName, as planned by
+// PlanCopyRecords. If any record fails to be created, the copies made so
+// far are removed again. It returns the applied changes, or the planned
+// changes if DryRun is set.
+func (p *Provider) CopyRecords(ctx context.Context, zone, fromName, toName string, opts CopyOptions) ([]Change, error) {
+	plan, err := p.PlanCopyRecords(ctx, zone, fromName, toName, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.DryRun {
+		return plan.Changes, nil
+	}
+
+	applied, err := p.ApplyPlan(ctx, plan)
+	if err != nil {
+		if rbErr := p.rollback(ctx, plan.Zone, applied); rbErr != nil {
+			return applied, fmt.Errorf("%w (rollback also failed: %v)", err, rbErr)
+		}
+		return nil, fmt.Errorf("copying records was rolled back: %w", err)
+	}
+
+	return applied, nil
+}
+
+// renameRecord maps a relative record name under from to the same position
+// under to. It reports false if name is not selected.
+func renameRecord(name, from, to string, includeSubdomains bool) (string, bool) {
+	if strings.EqualFold(name, from) {
+		return to, true
+	}
+	if !includeSubdomains {
+		return "", false
+	}
+
+	var prefix string
+	switch {
+	case from == "@":
+		prefix = name
+	case len(name) > len(from)+1 && strings.HasSuffix(strings.ToLower(name), "."+strings.ToLower(from)):
+		prefix = name[:len(name)-len(from)-1]
+	default:
+		return "", false
+	}
+
+	if to == "@" {
+		return prefix, true
+	}
+	return prefix + "." + to, true
+}
@@ -0,0 +1,149 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// defaultConcurrency bounds multi-zone operations when no limit is given.
+// It is kept low because DNSPod enforces per-account request limits.
+const defaultConcurrency = 4
+
+// ZoneResult is the outcome of a multi-zone operation for one zone
+type ZoneResult struct {
+	Zone    string
+	Records []libdns.Record
+	Changes []Change
+	Err     error
+}
+
+// GetRecordsMulti gets the records of several zones, running at most
+// concurrency requests at a time (0 means a conservative default). Results
+// are returned in the order of zones; the error joins every per-zone
+// failure.
+func (p *Provider) GetRecordsMulti(ctx context.Context, zones []string, concurrency int) ([]ZoneResult, error) {
+	return p.fanOut(ctx, zones, concurrency, func(ctx context.Context, zone string) ZoneResult {
+		records, err := p.GetRecords(ctx, zone)
+		return ZoneResult{Zone: zone, Records: records, Err: err}
+	})
+}
+
+// ApplyMulti runs fn for each zone with bounded concurrency and collects the
+// changes it reports, e.g. to rotate an SPF include across many domains:
+//
+//	p.ApplyMulti(ctx, zones, 4, func(ctx context.Context, zone string) ([]Change, error) {
+//		return p.ReplaceValue(ctx, zone, oldSPF, newSPF, "TXT")
+//	})
+func (p *Provider) ApplyMulti(ctx context.Context, zones []string, concurrency int, fn func(ctx context.Context, zone string) ([]Change, error)) ([]ZoneResult, error) {
+	return p.fanOut(ctx, zones, concurrency, func(ctx context.Context, zone string) ZoneResult {
+		changes, err := fn(ctx, zone)
+		return ZoneResult{Zone: zone, Changes: changes, Err: err}
+	})
+}
+
+// fanOut runs op for every zone with at most concurrency in flight
+func (p *Provider) fanOut(ctx context.Context, zones []string, concurrency int, op func(context.Context, string) ZoneResult) ([]ZoneResult, error) {
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	results := make([]ZoneResult, len(zones))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for i, zone := range zones {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			results[i] = ZoneResult{Zone: zone, Err: ctx.Err()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, zone string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i] = op(ctx, zone)
+		}(i, zone)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, result := range results {
+		if result.Err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %w", result.Zone, result.Err))
+		}
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// FindOptions configures FindByValue
+type FindOptions struct {
+	// Zones to search. If empty, every zone in the account is searched.
+	Zones []string
+
+	// Types restricts the search to the given record types
+	Types []string
+
+	// Concurrency bounds the number of zones searched at once
+	Concurrency int
+}
+
+// RecordMatch is a record found by FindByValue
+type RecordMatch struct {
+	Zone   string
+	Record libdns.Record
+}
+
+// FindByValue searches zones for records pointing at value, an IP address
+// or host name (compared as by ReplaceValue). It answers "what still points
+// at this server?" across the whole account. Zones that could not be
+// searched are reported in the error alongside the matches that were found.
+func (p *Provider) FindByValue(ctx context.Context, value string, opts FindOptions) ([]RecordMatch, error) {
+	zones := opts.Zones
+	if len(zones) == 0 {
+		domains, err := p.getClient().getDomains(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, d := range domains {
+			zones = append(zones, d.Name)
+		}
+	}
+
+	filter := RecordFilter{Types: opts.Types}
+	results, err := p.fanOut(ctx, zones, opts.Concurrency, func(ctx context.Context, zone string) ZoneResult {
+		client := p.getClient()
+		domainID, err := client.getDomainID(ctx, zone)
+		if err != nil {
+			return ZoneResult{Zone: zone, Err: err}
+		}
+
+		records, err := client.recordsPointingAt(ctx, domainID, value)
+		if err != nil {
+			return ZoneResult{Zone: zone, Err: err}
+		}
+
+		var found []libdns.Record
+		for _, rec := range records {
+			if !isSystemRecord(rec) && filter.matches(rec, zone) {
+				found = append(found, convertToLibDNSRecord(rec, zone))
+			}
+		}
+		return ZoneResult{Zone: zone, Records: found}
+	})
+
+	var matches []RecordMatch
+	for _, result := range results {
+		for _, rec := range result.Records {
+			matches = append(matches, RecordMatch{Zone: result.Zone, Record: rec})
+		}
+	}
+
+	return matches, err
+}
@@ -0,0 +1,92 @@
+package dnspod
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AbsoluteName returns the fully-qualified name, with a trailing dot, of a
+// record name in zone. name may be relative ("www"), "@" or empty for the
+// apex, or already absolute ("www.example.com."), in which case it is
+// returned unchanged. The zone may be given with or without trailing dot.
+//
+// This is the form in which the provider returns record names.
+func AbsoluteName(name, zone string) string {
+	return makeAbsoluteName(name, zone)
+}
+
+// RelativeName returns a record name relative to zone, as DNSPod expects
+// it: "@" for the apex and "www" for "www.example.com". Trailing dots on
+// either argument are ignored. Names outside the zone are returned without
+// their trailing dot; use ExtractSubdomain to detect them.
+//
+// This is the conversion the provider applies to input record names.
+func RelativeName(name, zone string) string {
+	return extractRecordName(name, zone)
+}
+
+// ExtractSubdomain returns the DNSPod sub_domain of name within zone, like
+// RelativeName, and reports whether name lies in the zone at all. Relative
+// names (without a trailing dot) are taken to be in the zone.
+func ExtractSubdomain(name, zone string) (string, bool) {
+	if !strings.HasSuffix(name, ".") {
+		if name == "" {
+			return "@", true
+		}
+		return extractRecordName(makeAbsoluteName(name, zone), zone), true
+	}
+
+	trimmedName := strings.TrimSuffix(name, ".")
+	trimmedZone := strings.TrimSuffix(zone, ".")
+	if trimmedName != trimmedZone && !strings.HasSuffix(trimmedName, "."+trimmedZone) {
+		return "", false
+	}
+	return extractRecordName(name, zone), true
+}
+
+// NormalizeName returns name in the canonical form the provider uses for
+// returned records: lower case, with characters that are special in zone
+// file presentation format escaped as in RFC 1035 ("\;" or "\ " inside a
+// label, "\DDD" for unprintable and non-ASCII bytes, as miekg/dns prints
+// them). Already escaped input is kept as is.
+func NormalizeName(name string) string {
+	var b strings.Builder
+	b.Grow(len(name))
+
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '\\' && i+1 < len(name):
+			// Keep existing escapes, lowercasing escaped letters
+			b.WriteByte(c)
+			i++
+			b.WriteByte(lowerASCII(name[i]))
+		case c == '.' || c == '*' || c == '-' || c == '_' ||
+			c >= '0' && c <= '9' || c >= 'a' && c <= 'z':
+			b.WriteByte(c)
+		case c >= 'A' && c <= 'Z':
+			b.WriteByte(lowerASCII(c))
+		case c >= ' ' && c < 0x7f:
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "\\%03d", c)
+		}
+	}
+
+	return b.String()
+}
+
+// lowerASCII lowercases an ASCII letter
+func lowerASCII(c byte) byte {
+	if c >= 'A' && c <= 'Z' {
+		return c + 'a' - 'A'
+	}
+	return c
+}
+
+// sameName reports whether two record names refer to the same name in
+// zone, regardless of case, escaping and whether they are relative
+func sameName(a, b, zone string) bool {
+	return NormalizeName(makeAbsoluteName(a, zone)) == NormalizeName(makeAbsoluteName(b, zone))
+}
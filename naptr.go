@@ -0,0 +1,142 @@
+package dnspod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// NAPTR represents a NAPTR record (RFC 3403), as used by SIP/VoIP and
+// ENUM deployments.
+type NAPTR struct {
+	Name        string
+	TTL         time.Duration
+	Order       uint16
+	Preference  uint16
+	Flags       string // e.g. "S", "A", "U", "P" or empty
+	Service     string // e.g. "SIP+D2U"
+	Regexp      string // substitution expression, usually empty when Replacement is set
+	Replacement string // next domain name to query, "." when Regexp is used
+
+	// Optional custom data associated with the provider serving this record.
+	ProviderData any
+}
+
+// RR implements libdns.Record
+func (n NAPTR) RR() libdns.RR {
+	replacement := n.Replacement
+	if replacement == "" {
+		replacement = "."
+	}
+	return libdns.RR{
+		Name: n.Name,
+		TTL:  n.TTL,
+		Type: "NAPTR",
+		Data: fmt.Sprintf("%d %d %s %s %s %s",
+			n.Order, n.Preference,
+			quoteNAPTRString(n.Flags), quoteNAPTRString(n.Service), quoteNAPTRString(n.Regexp),
+			replacement),
+	}
+}
+
+// parseNAPTR parses an "order preference flags service regexp replacement"
+// value in the format DNSPod stores it, with the three string fields quoted
+func parseNAPTR(name string, ttl time.Duration, value string) (NAPTR, error) {
+	fields, err := splitNAPTRFields(value)
+	if err != nil {
+		return NAPTR{}, fmt.Errorf("invalid NAPTR record value %q: %w", value, err)
+	}
+	if len(fields) != 6 {
+		return NAPTR{}, fmt.Errorf("invalid NAPTR record value %q: expected \"order preference flags service regexp replacement\"", value)
+	}
+
+	order, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return NAPTR{}, fmt.Errorf("invalid NAPTR record value %q: bad order", value)
+	}
+	preference, err := strconv.ParseUint(fields[1], 10, 16)
+	if err != nil {
+		return NAPTR{}, fmt.Errorf("invalid NAPTR record value %q: bad preference", value)
+	}
+
+	naptr := NAPTR{
+		Name:        name,
+		TTL:         ttl,
+		Order:       uint16(order),
+		Preference:  uint16(preference),
+		Flags:       fields[2],
+		Service:     fields[3],
+		Regexp:      fields[4],
+		Replacement: fields[5],
+	}
+
+	return naptr, validateNAPTRFields(naptr)
+}
+
+// validateNAPTRFields checks the flags and the regexp/replacement rule
+func validateNAPTRFields(n NAPTR) error {
+	for _, c := range n.Flags {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return fmt.Errorf("invalid NAPTR flags %q: only alphanumeric characters are allowed", n.Flags)
+		}
+	}
+	if n.Regexp != "" && n.Replacement != "" && n.Replacement != "." {
+		return fmt.Errorf("invalid NAPTR record: regexp and replacement are mutually exclusive")
+	}
+	return nil
+}
+
+// validateNAPTR checks a DNSPod NAPTR record value
+func validateNAPTR(rec record) error {
+	_, err := parseNAPTR(rec.Name, 0, rec.Value)
+	return err
+}
+
+// splitNAPTRFields splits a value on whitespace, keeping quoted strings
+// (with backslash escapes) together and unquoted
+func splitNAPTRFields(value string) ([]string, error) {
+	var fields []string
+	var current strings.Builder
+	inQuotes, escaped, started := false, false, false
+
+	for _, c := range value {
+		switch {
+		case escaped:
+			current.WriteRune(c)
+			escaped = false
+		case c == '\\' && inQuotes:
+			escaped = true
+		case c == '"':
+			inQuotes = !inQuotes
+			started = true
+		case (c == ' ' || c == '\t') && !inQuotes:
+			if started {
+				fields = append(fields, current.String())
+				current.Reset()
+				started = false
+			}
+		default:
+			current.WriteRune(c)
+			started = true
+		}
+	}
+
+	if inQuotes || escaped {
+		return nil, fmt.Errorf("unterminated quoted string")
+	}
+	if started {
+		fields = append(fields, current.String())
+	}
+
+	return fields, nil
+}
+
+// quoteNAPTRString quotes a character-string field for the record value
+func quoteNAPTRString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,251 @@
+package dnspod
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	// webhookAttempts is how many times a webhook delivery is tried
+	webhookAttempts = 3
+
+	// webhookBackoff is the delay before the first retry; it doubles
+	// after every failed attempt
+	webhookBackoff = 2 * time.Second
+
+	// webhookTimeout bounds a single delivery attempt
+	webhookTimeout = 10 * time.Second
+
+	// execNotifierTimeout bounds a notification command by default
+	execNotifierTimeout = 30 * time.Second
+)
+
+// ChangeEvent describes a mutation made through the provider. It is what
+// notifiers receive, and the JSON payload sent to webhooks.
+type ChangeEvent struct {
+	Time time.Time `json:"time"`
+	Zone string    `json:"zone"`
+
+	// Record is the fully-qualified name of the changed record
+	Record string    `json:"record"`
+	Op     JournalOp `json:"operation"`
+
+	Before []libdns.RR `json:"before,omitempty"`
+	After  []libdns.RR `json:"after,omitempty"`
+}
+
+// newChangeEvent describes a mutation
+func newChangeEvent(zone string, op JournalOp, before, after []libdns.Record) ChangeEvent {
+	event := ChangeEvent{
+		Time:   time.Now(),
+		Zone:   zone,
+		Op:     op,
+		Before: toRRs(before),
+		After:  toRRs(after),
+	}
+
+	switch {
+	case len(event.After) > 0:
+		event.Record = makeAbsoluteName(event.After[0].Name, zone)
+	case len(event.Before) > 0:
+		event.Record = makeAbsoluteName(event.Before[0].Name, zone)
+	}
+
+	return event
+}
+
+// Notifier receives change events after mutations. Implementations are
+// called in the background and must be safe for concurrent use.
+type Notifier interface {
+	Notify(ctx context.Context, event ChangeEvent) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, event ChangeEvent) error
+
+// Notify implements Notifier
+func (f NotifierFunc) Notify(ctx context.Context, event ChangeEvent) error {
+	return f(ctx, event)
+}
+
+// notify delivers a change event to the configured webhooks and notifiers
+// in the background, so that slow receivers do not hold up DNS changes.
+// Failed deliveries are reported through the warning handler.
+func (p *Provider) notify(zone string, op JournalOp, before, after []libdns.Record) {
+	if len(p.Webhooks) == 0 && len(p.Notifiers) == 0 {
+		return
+	}
+
+	notifiers := make([]Notifier, 0, len(p.Webhooks)+len(p.Notifiers))
+	for _, url := range p.Webhooks {
+		notifiers = append(notifiers, &WebhookNotifier{URL: url})
+	}
+	notifiers = append(notifiers, p.Notifiers...)
+
+	event := newChangeEvent(zone, op, before, after)
+	for _, n := range notifiers {
+		go func(n Notifier) {
+			if err := n.Notify(context.Background(), event); err != nil {
+				p.warn(fmt.Errorf("failed to send change notification: %w", err))
+			}
+		}(n)
+	}
+}
+
+// WebhookNotifier POSTs change events as JSON, retrying with exponential
+// backoff on network errors and non-2xx responses
+type WebhookNotifier struct {
+	URL string
+
+	// Headers are added to every request, e.g. for authentication
+	Headers map[string]string
+
+	// Attempts is how many times a delivery is tried. It defaults to 3.
+	Attempts int
+}
+
+// Notify implements Notifier
+func (n *WebhookNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode change event: %w", err)
+	}
+
+	attempts := n.Attempts
+	if attempts <= 0 {
+		attempts = webhookAttempts
+	}
+	backoff := webhookBackoff
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		lastErr = n.post(ctx, payload)
+		if lastErr == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("delivery to %s failed after %d attempts: %w", n.URL, attempts, lastErr)
+}
+
+// post makes a single delivery attempt
+func (n *WebhookNotifier) post(ctx context.Context, payload []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, webhookTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	for key, value := range n.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("HTTP error: %d %s", resp.StatusCode, resp.Status)
+	}
+	return nil
+}
+
+// StdoutNotifier writes each change event as a line of JSON, for log
+// shippers that collect standard output
+type StdoutNotifier struct {
+	// Writer receives the events. It defaults to os.Stdout.
+	Writer io.Writer
+
+	mutex sync.Mutex
+}
+
+// Notify implements Notifier
+func (n *StdoutNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode change event: %w", err)
+	}
+
+	w := n.Writer
+	if w == nil {
+		w = os.Stdout
+	}
+
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// ExecNotifier runs a command for each change event, with the event as
+// JSON on standard input. The zone, record name and operation are also
+// passed in the DNSPOD_ZONE, DNSPOD_RECORD and DNSPOD_OPERATION
+// environment variables, so that simple scripts can forward alerts to
+// Slack, DingTalk or email.
+type ExecNotifier struct {
+	Command string
+	Args    []string
+
+	// Timeout bounds each run. It defaults to 30 seconds.
+	Timeout time.Duration
+}
+
+// Notify implements Notifier
+func (n *ExecNotifier) Notify(ctx context.Context, event ChangeEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode change event: %w", err)
+	}
+
+	timeout := n.Timeout
+	if timeout <= 0 {
+		timeout = execNotifierTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, n.Command, n.Args...)
+	cmd.Stdin = bytes.NewReader(data)
+	cmd.Env = append(os.Environ(),
+		"DNSPOD_ZONE="+event.Zone,
+		"DNSPOD_RECORD="+event.Record,
+		"DNSPOD_OPERATION="+string(event.Op),
+	)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w: %s", n.Command, err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+// Interface guards
+var (
+	_ Notifier = (*WebhookNotifier)(nil)
+	_ Notifier = (*StdoutNotifier)(nil)
+	_ Notifier = (*ExecNotifier)(nil)
+)
@@ -0,0 +1,184 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseError reports a value from the API that could not be parsed
+type ParseError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("invalid %s %q: %s", e.Field, e.Value, e.Reason)
+}
+
+// maxTTL is the largest TTL a record can carry (RFC 2181 section 8)
+const maxTTL = 1<<31 - 1
+
+// ParseTTL parses a record TTL given in seconds
+func ParseTTL(s string) (time.Duration, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 32)
+	if err != nil {
+		return 0, &ParseError{Field: "TTL", Value: s, Reason: "not a number of seconds"}
+	}
+	if n > maxTTL {
+		return 0, &ParseError{Field: "TTL", Value: s, Reason: "out of range"}
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// ParseEnabled parses the enabled flag of a record, "1" or "0"
+func ParseEnabled(s string) (bool, error) {
+	switch strings.TrimSpace(s) {
+	case "1":
+		return true, nil
+	case "0":
+		return false, nil
+	}
+	return false, &ParseError{Field: "enabled flag", Value: s, Reason: `expected "1" or "0"`}
+}
+
+// ParseWeight parses the weight of a record, between 0 and 100. It reports
+// false if the record has no weight, which DNSPod returns as an empty
+// value or null.
+func ParseWeight(s string) (int, bool, error) {
+	s = strings.Trim(strings.TrimSpace(s), `"`)
+	if s == "" || s == "null" {
+		return 0, false, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, false, &ParseError{Field: "weight", Value: s, Reason: "not a number"}
+	}
+	if n < 0 || n > 100 {
+		return 0, false, &ParseError{Field: "weight", Value: s, Reason: "must be between 0 and 100"}
+	}
+	return n, true, nil
+}
+
+// ParseMXPreference parses the mx field of an MX record
+func ParseMXPreference(s string) (uint16, error) {
+	n, err := strconv.ParseUint(strings.TrimSpace(s), 10, 16)
+	if err != nil {
+		return 0, &ParseError{Field: "MX preference", Value: s, Reason: "not a number between 0 and 65535"}
+	}
+	return uint16(n), nil
+}
+
+// SRVValue is the parsed value of an SRV record
+type SRVValue struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// ParseSRVValue parses the value of an SRV record, "priority weight port
+// target". The form "weight port target" is accepted too, with Priority
+// left zero, for values whose priority DNSPod carries in the mx field.
+func ParseSRVValue(s string) (SRVValue, error) {
+	fail := func(reason string) (SRVValue, error) {
+		return SRVValue{}, &ParseError{Field: "SRV value", Value: s, Reason: reason}
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) != 3 && len(fields) != 4 {
+		return fail("expected priority, weight, port and target")
+	}
+
+	numbers := make([]uint16, len(fields)-1)
+	for i, field := range fields[:len(fields)-1] {
+		n, err := strconv.ParseUint(field, 10, 16)
+		if err != nil {
+			return fail(fmt.Sprintf("%q is not a number between 0 and 65535", field))
+		}
+		numbers[i] = uint16(n)
+	}
+
+	v := SRVValue{Target: fields[len(fields)-1]}
+	if len(numbers) == 3 {
+		v.Priority, numbers = numbers[0], numbers[1:]
+	}
+	v.Weight, v.Port = numbers[0], numbers[1]
+	return v, nil
+}
+
+// CAAValue is the parsed value of a CAA record
+type CAAValue struct {
+	Flags uint8
+	Tag   string
+	Value string
+}
+
+// ParseCAAValue parses the value of a CAA record, `flags tag "value"`. The
+// value may be unquoted.
+func ParseCAAValue(s string) (CAAValue, error) {
+	fail := func(reason string) (CAAValue, error) {
+		return CAAValue{}, &ParseError{Field: "CAA value", Value: s, Reason: reason}
+	}
+
+	fields := strings.SplitN(strings.TrimSpace(s), " ", 3)
+	if len(fields) != 3 {
+		return fail("expected flags, tag and value")
+	}
+
+	flags, err := strconv.ParseUint(fields[0], 10, 8)
+	if err != nil {
+		return fail(fmt.Sprintf("flags %q are not a number between 0 and 255", fields[0]))
+	}
+
+	tag := fields[1]
+	if tag == "" {
+		return fail("empty tag")
+	}
+	for _, c := range tag {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9') {
+			return fail(fmt.Sprintf("tag %q is not alphanumeric", tag))
+		}
+	}
+
+	value := strings.TrimSpace(fields[2])
+	if strings.HasPrefix(value, `"`) {
+		unquoted, err := strconv.Unquote(value)
+		if err != nil {
+			return fail("badly quoted value")
+		}
+		value = unquoted
+	}
+
+	return CAAValue{Flags: uint8(flags), Tag: strings.ToLower(tag), Value: value}, nil
+}
+
+// UnmarshalJSON decodes a Record.List record, accepting the weight as a
+// number, a string or null
+func (r *record) UnmarshalJSON(data []byte) error {
+	type plain record
+	aux := struct {
+		*plain
+		Weight json.RawMessage `json:"weight"`
+	}{plain: (*plain)(r)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	r.Weight = rawWeight(aux.Weight)
+	return nil
+}
+
+// rawWeight returns a JSON weight as a string, empty if it is null
+func rawWeight(raw json.RawMessage) string {
+	weight := strings.Trim(string(raw), `"`)
+	if weight == "null" {
+		return ""
+	}
+	return weight
+}
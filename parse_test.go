@@ -0,0 +1,78 @@
+package dnspod
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func FuzzParseTTL(f *testing.F) {
+	for _, seed := range []string{"600", " 1 ", "0", "2147483647", "2147483648", "-1", "1e3", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		ttl, err := ParseTTL(s)
+		if err != nil {
+			return
+		}
+		formatted := strconv.Itoa(int(ttl.Seconds()))
+		again, err := ParseTTL(formatted)
+		if err != nil || again != ttl {
+			t.Errorf("ParseTTL(%q) = %v, formatted as %q parses to %v, %v", s, ttl, formatted, again, err)
+		}
+	})
+}
+
+func FuzzParseSRVValue(f *testing.F) {
+	for _, seed := range []string{"10 20 5060 sip.example.com.", "20 5060 .", "1 2 3", "65536 1 1 t", " 0\t0 0  t ", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		v, err := ParseSRVValue(s)
+		if err != nil {
+			return
+		}
+		formatted := fmt.Sprintf("%d %d %d %s", v.Priority, v.Weight, v.Port, v.Target)
+		again, err := ParseSRVValue(formatted)
+		if err != nil || again != v {
+			t.Errorf("ParseSRVValue(%q) = %+v, formatted as %q parses to %+v, %v", s, v, formatted, again, err)
+		}
+	})
+}
+
+func FuzzParseCAAValue(f *testing.F) {
+	for _, seed := range []string{`0 issue "letsencrypt.org"`, "128 iodef mailto:a@example.com", `0 issue "a\"b"`, `0 issue "`, "256 issue x", "0 is-sue x", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		v, err := ParseCAAValue(s)
+		if err != nil {
+			return
+		}
+		// Format it the way records are sent
+		formatted := convertFromLibDNSRecord(libdns.CAA{Name: "@", Flags: v.Flags, Tag: v.Tag, Value: v.Value}, "example.com.").Value
+		again, err := ParseCAAValue(formatted)
+		if err != nil || again != v {
+			t.Errorf("ParseCAAValue(%q) = %+v, formatted as %q parses to %+v, %v", s, v, formatted, again, err)
+		}
+	})
+}
+
+func FuzzParseWeight(f *testing.F) {
+	for _, seed := range []string{"0", "100", "101", `"50"`, "null", "", " 7 ", "-1"} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		n, ok, err := ParseWeight(s)
+		if err != nil || !ok {
+			return
+		}
+		formatted := strconv.Itoa(n)
+		again, ok, err := ParseWeight(formatted)
+		if err != nil || !ok || again != n {
+			t.Errorf("ParseWeight(%q) = %d, formatted as %q parses to %d, %v, %v", s, n, formatted, again, ok, err)
+		}
+	})
+}
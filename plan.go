@@ -0,0 +1,285 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ChangeOp is the kind of change made to a record
+type ChangeOp string
+
+const (
+	ChangeCreate ChangeOp = "create"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// Change describes a single record change. Before is nil for creates and
+// After is nil for deletes. Changes returned after applying hold the
+// records as stored by DNSPod.
+type Change struct {
+	Op     ChangeOp
+	Before libdns.Record
+	After  libdns.Record
+
+	// existing is the DNSPod record the change applies to
+	existing *record
+}
+
+// Plan is an ordered list of changes to a zone. Plans are produced by the
+// bulk helpers so that they can be reviewed (dry run) before ApplyPlan
+// executes them.
+type Plan struct {
+	Zone    string
+	Changes []Change
+}
+
+// Empty reports whether the plan has no changes
+func (pl *Plan) Empty() bool {
+	return pl == nil || len(pl.Changes) == 0
+}
+
+// ApplyPlan executes the changes in a plan in order. If an Approver is
+// configured it is consulted first, and a rejected plan returns
+// ErrPlanRejected without changing anything. Otherwise ApplyPlan stops at
+// the first failure and returns the changes that were applied, with After
+// holding the record as stored by DNSPod.
+func (p *Provider) ApplyPlan(ctx context.Context, plan *Plan) ([]Change, error) {
+	if plan.Empty() {
+		return nil, nil
+	}
+
+	if p.Approver != nil {
+		approved, err := p.Approver.Approve(ctx, plan)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get plan approval: %w", err)
+		}
+		if !approved {
+			return nil, ErrPlanRejected
+		}
+	}
+
+	return p.applyPlan(ctx, plan)
+}
+
+// approveAndApply plans a change to a zone with planFn and applies the
+// plan. The approver is asked without holding the zone lock, so that a
+// slow approval does not hold up other writes; the zone is then planned
+// again under the lock, and if the plan differs from the approved one,
+// nothing is applied and ErrPlanChanged is returned.
+func (p *Provider) approveAndApply(ctx context.Context, zone string, planFn func(context.Context) (*Plan, error)) (*Plan, []Change, error) {
+	var approved *Plan
+	if p.Approver != nil {
+		plan, err := planFn(ctx)
+		if err != nil || plan.Empty() {
+			return plan, nil, err
+		}
+
+		ok, err := p.Approver.Approve(ctx, plan)
+		if err != nil {
+			return plan, nil, fmt.Errorf("failed to get plan approval: %w", err)
+		}
+		if !ok {
+			return plan, nil, ErrPlanRejected
+		}
+		approved = plan
+	}
+
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return approved, nil, err
+	}
+	defer unlock()
+
+	plan, err := planFn(ctx)
+	if err != nil {
+		return plan, nil, err
+	}
+	if approved != nil && !samePlan(approved, plan) {
+		return plan, nil, ErrPlanChanged
+	}
+
+	applied, err := p.applyPlan(ctx, plan)
+	return plan, applied, err
+}
+
+// samePlan reports whether two plans make the same changes to the same
+// records
+func samePlan(a, b *Plan) bool {
+	if len(a.Changes) != len(b.Changes) {
+		return false
+	}
+	for i, change := range a.Changes {
+		other := b.Changes[i]
+		if change.Op != other.Op || (change.existing == nil) != (other.existing == nil) {
+			return false
+		}
+		if change.existing != nil && *change.existing != *other.existing {
+			return false
+		}
+		if !sameLibDNSRecord(change.After, other.After) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameLibDNSRecord reports whether two records, either of which may be
+// nil, have the same name, type, TTL and value
+func sameLibDNSRecord(a, b libdns.Record) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	rrA, rrB := a.RR(), b.RR()
+	return rrA.Name == rrB.Name && strings.EqualFold(rrA.Type, rrB.Type) &&
+		rrA.TTL == rrB.TTL && sameRecordValue(rrA.Type, rrA.Data, rrB.Data)
+}
+
+// applyPlan executes a plan without consulting the approver
+func (p *Provider) applyPlan(ctx context.Context, plan *Plan) ([]Change, error) {
+	ctx, unlock, err := p.lockZone(ctx, plan.Zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	if plan.Empty() {
+		return nil, nil
+	}
+
+	client := p.getClient()
+	zone := plan.Zone
+
+	domainID, err := client.getDomainID(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
+	}
+
+	var applied []Change
+	for i := 0; i < len(plan.Changes); i++ {
+		change := plan.Changes[i]
+		switch change.Op {
+		case ChangeCreate:
+			rec, err := p.prepareRecord(client, change.After, zone)
+			if err != nil {
+				return applied, err
+			}
+
+			createdRec, writeErr := p.createRecord(ctx, client, zone, domainID, rec)
+			if createdRec == nil {
+				return applied, fmt.Errorf("failed to create record %s: %w", change.After.RR().Name, writeErr)
+			}
+
+			newLibRec := convertToLibDNSRecord(*createdRec, zone)
+			applied = append(applied, Change{Op: ChangeCreate, After: newLibRec, existing: createdRec})
+
+			if err := p.journal(ctx, zone, JournalAppend, nil, []libdns.Record{newLibRec}); err != nil {
+				return applied, err
+			}
+			if writeErr != nil {
+				return applied, writeErr
+			}
+
+		case ChangeUpdate:
+			// Consecutive updates are applied together, so that those
+			// changing the same field to the same value can be batched
+			end := i + 1
+			for end < len(plan.Changes) && plan.Changes[end].Op == ChangeUpdate {
+				end++
+			}
+			updated, err := p.applyUpdates(ctx, client, zone, domainID, plan.Changes[i:end])
+			applied = append(applied, updated...)
+			if err != nil {
+				return applied, err
+			}
+			i = end - 1
+
+		case ChangeDelete:
+			if change.existing == nil {
+				return applied, fmt.Errorf("delete of %s has no existing record", change.Before.RR().Name)
+			}
+
+			if err := client.deleteRecord(ctx, domainID, change.existing.ID); err != nil {
+				return applied, fmt.Errorf("failed to delete record %s: %w", change.Before.RR().Name, err)
+			}
+			applied = append(applied, Change{Op: ChangeDelete, Before: change.Before})
+
+			if err := p.journal(ctx, zone, JournalDelete, []libdns.Record{change.Before}, nil); err != nil {
+				return applied, err
+			}
+
+		default:
+			return applied, fmt.Errorf("unknown change operation %q", change.Op)
+		}
+	}
+
+	return applied, nil
+}
+
+// applyUpdates applies update changes in place, keeping the lines of the
+// records, and returns those applied in the order of changes
+func (p *Provider) applyUpdates(ctx context.Context, client *Client, zone, domainID string, changes []Change) ([]Change, error) {
+	existing := make([]record, len(changes))
+	recs := make([]record, len(changes))
+	for i, change := range changes {
+		if change.existing == nil {
+			return nil, fmt.Errorf("update of %s has no existing record", change.After.RR().Name)
+		}
+
+		rec, err := p.prepareRecord(client, change.After, zone)
+		if err != nil {
+			return nil, err
+		}
+		rec.Line = change.existing.Line
+		rec.LineID = change.existing.LineID
+		if !usesMX(rec.Type) {
+			rec.MX = ""
+		}
+		existing[i] = *change.existing
+		recs[i] = rec
+	}
+
+	updated := make([]*Change, len(changes))
+	err := p.updateRecords(ctx, client, zone, domainID, existing, recs, func(i int, updatedRec *record) error {
+		newLibRec := convertToLibDNSRecord(*updatedRec, zone)
+		updated[i] = &Change{Op: ChangeUpdate, Before: changes[i].Before, After: newLibRec, existing: changes[i].existing}
+		previous := convertToLibDNSRecord(existing[i], zone)
+		return p.journal(ctx, zone, JournalSet, []libdns.Record{previous}, []libdns.Record{newLibRec})
+	})
+
+	var applied []Change
+	for _, change := range updated {
+		if change != nil {
+			applied = append(applied, *change)
+		}
+	}
+	return applied, err
+}
+
+// rollback reverts applied changes, newest first, on a best-effort basis.
+// It returns the first error encountered but keeps going so that as much
+// as possible is restored.
+func (p *Provider) rollback(ctx context.Context, zone string, applied []Change) error {
+	var firstErr error
+	for i := len(applied) - 1; i >= 0; i-- {
+		change := applied[i]
+
+		var inverse Change
+		switch change.Op {
+		case ChangeCreate:
+			inverse = Change{Op: ChangeDelete, Before: change.After, existing: change.existing}
+		case ChangeUpdate:
+			inverse = Change{Op: ChangeUpdate, Before: change.After, After: change.Before, existing: change.existing}
+		case ChangeDelete:
+			inverse = Change{Op: ChangeCreate, After: change.Before}
+		}
+
+		if _, err := p.applyPlan(ctx, &Plan{Zone: zone, Changes: []Change{inverse}}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
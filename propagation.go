@@ -0,0 +1,219 @@
+package dnspod
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+const (
+	// dohMediaType is the content type of RFC 8484 DNS-over-HTTPS messages
+	dohMediaType = "application/dns-message"
+
+	// defaultPropagationTimeout is how long to wait for a record to become
+	// visible. DNSPod usually publishes within a minute but can take
+	// several under load.
+	defaultPropagationTimeout = 5 * time.Minute
+
+	// defaultPollInterval is the time between propagation checks
+	defaultPollInterval = 5 * time.Second
+)
+
+// Timeout returns the propagation timeout and poll interval, with defaults
+// applied. It matches the wait hint interface of ACME clients such as lego.
+func (p *Provider) Timeout() (timeout, interval time.Duration) {
+	timeout, interval = p.PropagationTimeout, p.PollInterval
+	if timeout <= 0 {
+		timeout = defaultPropagationTimeout
+	}
+	if interval <= 0 {
+		interval = defaultPollInterval
+	}
+	return timeout, interval
+}
+
+// WaitForPropagation polls the configured resolvers until enough of them
+// (PropagationQuorum, by default all) answer with rec, or the propagation
+// timeout expires. Without configured resolvers, the zone's DNSPod
+// nameservers are asked directly. It is meant to be called after presenting
+// an ACME DNS-01 challenge.
+func (p *Provider) WaitForPropagation(ctx context.Context, zone string, rec libdns.Record) error {
+	servers, err := p.propagationServers(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	quorum := p.PropagationQuorum
+	if quorum <= 0 || quorum > len(servers) {
+		quorum = len(servers)
+	}
+
+	timeout, interval := p.Timeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	rr := rec.RR()
+	name := makeAbsoluteName(rr.Name, zone)
+
+	for {
+		pending, lastErr := checkPropagation(ctx, servers, name, rr)
+		confirmed := len(servers) - len(pending)
+		if confirmed >= quorum {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			if lastErr == nil {
+				lastErr = ctx.Err()
+			}
+			return fmt.Errorf("%s record %s seen by %d of %d servers, %d required (missing from %s): %w",
+				rr.Type, name, confirmed, len(servers), quorum, strings.Join(pending, ", "), lastErr)
+		case <-time.After(interval):
+		}
+	}
+}
+
+// propagationServers returns the addresses of the servers to check
+func (p *Provider) propagationServers(ctx context.Context, zone string) ([]string, error) {
+	resolvers := append([]string(nil), p.Resolvers...)
+	if len(resolvers) == 0 || p.CheckAuthoritative {
+		nameServers, err := p.GetZoneNameServers(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+		resolvers = append(resolvers, nameServers...)
+	}
+	if len(resolvers) == 0 {
+		return nil, fmt.Errorf("no nameservers to check propagation of zone %s against", zone)
+	}
+
+	servers := make([]string, 0, len(resolvers))
+	for _, resolver := range resolvers {
+		servers = append(servers, resolverAddress(resolver))
+	}
+	return servers, nil
+}
+
+// checkPropagation queries each server for the record and returns those
+// that do not serve it yet, along with the last query error
+func checkPropagation(ctx context.Context, servers []string, name string, rr libdns.RR) ([]string, error) {
+	qtype, ok := dns.StringToType[strings.ToUpper(rr.Type)]
+	if !ok {
+		return servers, fmt.Errorf("unknown record type %s", rr.Type)
+	}
+
+	msg := new(dns.Msg)
+	msg.SetQuestion(dns.Fqdn(name), qtype)
+
+	client := new(dns.Client)
+
+	var pending []string
+	var lastErr error
+	for _, server := range servers {
+		resp, err := exchange(ctx, client, msg, server)
+		if err != nil {
+			lastErr = err
+			pending = append(pending, server)
+			continue
+		}
+		if !answers(resp, qtype, rr) {
+			pending = append(pending, server)
+		}
+	}
+
+	return pending, lastErr
+}
+
+// answers reports whether a response contains the record
+func answers(resp *dns.Msg, qtype uint16, rr libdns.RR) bool {
+	for _, answer := range resp.Answer {
+		if answer.Header().Rrtype != qtype {
+			continue
+		}
+		if sameRecordValue(rr.Type, answerValue(answer), rr.Data) {
+			return true
+		}
+	}
+	return false
+}
+
+// answerValue returns the data of an answer in libdns form
+func answerValue(answer dns.RR) string {
+	if txt, ok := answer.(*dns.TXT); ok {
+		return strings.Join(txt.Txt, "")
+	}
+	return strings.TrimPrefix(answer.String(), answer.Header().String())
+}
+
+// exchange sends a query to a server, over DNS-over-HTTPS if the server is
+// an https:// URL and over plain DNS otherwise
+func exchange(ctx context.Context, client *dns.Client, msg *dns.Msg, server string) (*dns.Msg, error) {
+	if !isDoH(server) {
+		resp, _, err := client.ExchangeContext(ctx, msg, server)
+		return resp, err
+	}
+
+	// RFC 8484 recommends ID 0 so that responses are cacheable
+	query := msg.Copy()
+	query.Id = 0
+	packed, err := query.Pack()
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack query: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, server, bytes.NewReader(packed))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+	req.Header.Set("User-Agent", userAgent)
+
+	httpResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("DoH query to %s failed: %w", server, err)
+	}
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("DoH query to %s failed with HTTP status %d", server, httpResp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(httpResp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DoH response from %s: %w", server, err)
+	}
+
+	resp := new(dns.Msg)
+	if err := resp.Unpack(body); err != nil {
+		return nil, fmt.Errorf("failed to unpack DoH response from %s: %w", server, err)
+	}
+	return resp, nil
+}
+
+// isDoH reports whether a resolver is a DNS-over-HTTPS URL
+func isDoH(resolver string) bool {
+	return strings.HasPrefix(strings.ToLower(resolver), "https://")
+}
+
+// resolverAddress adds the default DNS port to a resolver if it has none.
+// DNS-over-HTTPS URLs are returned unchanged.
+func resolverAddress(resolver string) string {
+	if isDoH(resolver) {
+		return resolver
+	}
+	resolver = strings.TrimSuffix(resolver, ".")
+	if _, _, err := net.SplitHostPort(resolver); err == nil {
+		return resolver
+	}
+	return net.JoinHostPort(strings.Trim(resolver, "[]"), "53")
+}
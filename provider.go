@@ -3,6 +3,9 @@ package dnspod
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/libdns/libdns"
 )
@@ -11,19 +14,339 @@ import (
 type Provider struct {
 	// LoginToken is the DNSPod API login token in format "id,token"
 	// See https://docs.dnspod.com/api/common-request-parameters/
-	LoginToken string `json:"login_token"`
+	// It may be a placeholder such as "{env.DNSPOD_TOKEN}".
+	LoginToken string `json:"login_token,omitempty"`
+
+	// SecretID and SecretKey are Tencent Cloud API keys, an alternative to
+	// LoginToken. When they are set, requests go to DNSPod API 3.0
+	// (https://dnspod.tencentcloudapi.com) and are signed with
+	// TC3-HMAC-SHA256; Endpoint may still point elsewhere, e.g. at a
+	// proxy. Both may be placeholders.
+	SecretID  string `json:"secret_id,omitempty"`
+	SecretKey string `json:"secret_key,omitempty"`
+
+	// Endpoint is the API base URL. It defaults to https://dnsapi.cn; use
+	// https://api.dnspod.com for accounts on the international site.
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Language selects the language of messages in API errors: "cn" or
+	// "en". It defaults to "cn" for dnsapi.cn and "en" for the
+	// international API.
+	Language string `json:"language,omitempty"`
+
+	// DefaultLine is the line new records are created on. It defaults to
+	// the endpoint's default line, "默认" for dnsapi.cn and "default" for
+	// the international API, and may be an alias or a name in either
+	// language.
+	DefaultLine string `json:"default_line,omitempty"`
+
+	// LineAliases maps custom line names to DNSPod line names, e.g.
+	// {"gd-telecom": "广东电信"}. Lines not known to this package are
+	// passed to DNSPod unchanged, so aliases are only needed for naming.
+	LineAliases map[string]string `json:"line_aliases,omitempty"`
+
+	// LineIDs maps DNSPod line names to line IDs, for enterprise plans
+	// with ISP- or region-specific lines whose names are ambiguous. Known
+	// IDs are sent along with the name and take precedence over it.
+	LineIDs map[string]string `json:"line_ids,omitempty"`
+
+	// Mirrors are alternative base URLs for the same backend as Endpoint,
+	// tried in order when it cannot be connected to. Mirrors given by IP
+	// address (e.g. "https://1.2.3.4") are sent Endpoint's host name.
+	Mirrors []string `json:"mirrors,omitempty"`
+
+	// HedgeAfter, if set, sends a second copy of a zone or record listing
+	// that has not completed after this long and uses whichever answers
+	// first. It cuts tail latency on lossy links at the cost of extra
+	// requests against the account's rate limit.
+	HedgeAfter time.Duration `json:"hedge_after,omitempty"`
+
+	// RateLimits caps requests per second by API action, e.g.
+	// {"Record.Ddns": 1, "Record.Modify": 5}, so heavy traffic on one
+	// action does not exhaust DNSPod's limit for the others. The "*" entry
+	// applies to every action not listed.
+	RateLimits map[string]float64 `json:"rate_limits,omitempty"`
+
+	// AdaptiveThrottle halves the request rate of an action whenever DNSPod
+	// reports its frequency limit was exceeded, then slowly recovers it on
+	// success. Actions without a configured limit start at 10 requests per
+	// second. The current rates are reported by Stats.
+	AdaptiveThrottle bool `json:"adaptive_throttle,omitempty"`
+
+	// MaxRetries is how often a failed request is retried. Frequency-limit
+	// errors and connection failures are retried for every action; server
+	// errors and timeouts only for reads, since a write may already have
+	// been applied. It defaults to 3; a negative value disables retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+
+	// RetryBackoff is the delay before the first retry, doubled for each
+	// further one up to 30 seconds, with random jitter. It defaults to one
+	// second.
+	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
+
+	// IncludeSystemRecords makes GetRecords return the apex NS records
+	// DNSPod manages itself, plus a synthesized SOA record. They are marked
+	// with RecordMetadata.System and are never modified by SetRecords or
+	// DeleteRecords.
+	IncludeSystemRecords bool `json:"include_system_records,omitempty"`
+
+	// Strict makes any lossy or guessed record conversion (unparseable
+	// values falling back to raw RRs, TTLs that would be rounded or sent
+	// as zero, lines that would be ignored or dropped) fail with a
+	// ConversionError instead of proceeding
+	Strict bool `json:"strict,omitempty"`
+
+	// ErrorOnEmpty makes GetRecords and GetRawRecords return ErrNoRecords
+	// for a zone without records (other than the system records that are
+	// filtered out), so callers can tell an empty zone from a failed call
+	// with errors.Is. Without it, they return an empty, non-nil slice.
+	ErrorOnEmpty bool `json:"error_on_empty,omitempty"`
+
+	// SkipInactiveZones makes operations on paused, locked or spam-flagged
+	// zones fail fast with an InactiveZoneError, which also matches
+	// ErrZoneNotFound, instead of calling the API
+	SkipInactiveZones bool `json:"skip_inactive_zones,omitempty"`
+
+	// DomainCacheTTL is how long the list of domains in the account is
+	// cached. It defaults to 10 minutes; a negative value disables the
+	// cache. Domains missing from the cached list are looked up again
+	// regardless, so that newly added domains are found.
+	DomainCacheTTL time.Duration `json:"domain_cache_ttl,omitempty"`
+
+	// RecordCacheTTL enables caching of record listings for this long. The
+	// cache of a zone is dropped whenever it is written to through this
+	// provider, but changes made elsewhere are not seen until it expires.
+	RecordCacheTTL time.Duration `json:"record_cache_ttl,omitempty"`
+
+	// TTLCoercion controls whether a TTL silently changed by DNSPod (for
+	// example raised to the plan minimum) is ignored, reported through
+	// OnWarning, or returned as a TTLCoercionError. Checking costs one
+	// extra request per written record.
+	TTLCoercion TTLPolicy `json:"ttl_coercion,omitempty"`
+
+	// Verify re-reads every created or updated record and returns a
+	// VerificationError if the stored value, line or TTL differ from what
+	// was requested. The TTL is left to TTLCoercion when that is set.
+	// Verifying costs one extra request per written record.
+	Verify bool `json:"verify,omitempty"`
+
+	// PropagationTimeout bounds how long WaitForPropagation waits for a
+	// record to become visible. It defaults to 5 minutes.
+	PropagationTimeout time.Duration `json:"propagation_timeout,omitempty"`
+
+	// PollInterval is the time between propagation checks. It defaults to
+	// 5 seconds.
+	PollInterval time.Duration `json:"poll_interval,omitempty"`
+
+	// Resolvers are the DNS servers ("host" or "host:port") asked during
+	// propagation checks. Entries starting with https:// are queried over
+	// DNS-over-HTTPS, for networks where plain DNS is intercepted. If
+	// empty, the zone's DNSPod nameservers are asked directly.
+	Resolvers []string `json:"resolvers,omitempty"`
+
+	// CheckAuthoritative adds the zone's DNSPod nameservers to Resolvers
+	// during propagation checks
+	CheckAuthoritative bool `json:"check_authoritative,omitempty"`
+
+	// PropagationQuorum is how many of the checked servers must serve a
+	// record before it counts as propagated. Zero means all of them.
+	PropagationQuorum int `json:"propagation_quorum,omitempty"`
+
+	// SerializeZoneWrites funnels all mutations of a zone through a single
+	// queue, in arrival order, so that concurrent writers (such as parallel
+	// certificate issuances for subdomains) do not hit DNSPod conflict
+	// errors or overwrite each other's changes. It only coordinates writes
+	// made through this provider.
+	SerializeZoneWrites bool `json:"serialize_zone_writes,omitempty"`
+
+	// SingleWriter serializes all mutations made through this provider,
+	// whatever zone they target, so that no two list-then-modify sequences
+	// ever interleave. It trades throughput for safety.
+	SingleWriter bool `json:"single_writer,omitempty"`
+
+	// CoalesceWindow, if set, buffers SetRecords calls for a zone for this
+	// long (e.g. 500ms) and sends them as one. As with consecutive calls,
+	// each RRset (name, type and line) ends up with the records of the last
+	// call that set it. Every caller waits for the flush and gets the
+	// resulting changes for its RRsets, which reduces API calls when tools
+	// emit many small updates in quick succession.
+	CoalesceWindow time.Duration `json:"coalesce_window,omitempty"`
+
+	// IdempotencyWindow, if set, makes creates safe to retry: before a
+	// record is created, an identical record (same name, type, value and
+	// line) that DNSPod reports as changed within this window is adopted
+	// instead, so that a retry after an ambiguous failure such as a timeout
+	// does not fail or duplicate the record. Each create then lists the
+	// zone first, from the record cache when RecordCacheTTL is set.
+	IdempotencyWindow time.Duration `json:"idempotency_window,omitempty"`
+
+	// OnWarning receives non-fatal problems. If nil, they are written to
+	// the standard logger.
+	OnWarning func(error) `json:"-"`
+
+	// Journal, if set, records every mutation made through this provider
+	// so that it can later be replayed or inverted
+	Journal *Journal `json:"-"`
+
+	// Webhooks are URLs that receive a JSON ChangeEvent by POST after every
+	// mutation. Deliveries happen in the background and are retried; final
+	// failures are reported through OnWarning.
+	Webhooks []string `json:"webhooks,omitempty"`
+
+	// Notifiers receive a ChangeEvent after every mutation, like Webhooks.
+	// See WebhookNotifier, StdoutNotifier and ExecNotifier.
+	Notifiers []Notifier `json:"-"`
+
+	// Approver, if set, is consulted before any plan is applied
+	Approver Approver `json:"-"`
 
 	client *Client
 }
 
-// getClient returns an initialized client, creating one if needed
+// clientMutex guards the creation of providers' clients. It is not a
+// Provider field because providers are copied by value, for example when
+// marshaled.
+var clientMutex sync.Mutex
+
+// getClient returns an initialized client, creating one if needed. If the
+// configuration is invalid, the client's requests fail with the error.
+// Concurrent first calls share one client, so that its locks, queues and
+// pending batches cover all of them.
 func (p *Provider) getClient() *Client {
+	clientMutex.Lock()
+	defer clientMutex.Unlock()
+
 	if p.client == nil {
-		p.client = newClient(p.LoginToken)
+		client, err := p.buildClient()
+		if err != nil {
+			client.configErr = err
+		}
+		p.client = client
 	}
 	return p.client
 }
 
+// buildClient creates a client from the provider configuration, resolving
+// placeholders. A client is returned even if this fails, so that its
+// caches and queues can be used; its requests must not be sent then.
+func (p *Provider) buildClient() (*Client, error) {
+	loginToken, tokenErr := expandPlaceholders(p.LoginToken)
+	endpoint, endpointErr := expandPlaceholders(p.Endpoint)
+	secretID, secretIDErr := expandPlaceholders(p.SecretID)
+	secretKey, secretKeyErr := expandPlaceholders(p.SecretKey)
+
+	useAPIv3 := p.SecretID != "" || p.SecretKey != ""
+	if useAPIv3 && endpoint == "" {
+		endpoint = v3BaseURL
+	}
+
+	client := newClient(loginToken, endpoint)
+
+	// Without both keys, API 3.0 requests would go out unsigned
+	if useAPIv3 && (secretID == "" || secretKey == "") {
+		return client, fmt.Errorf("secret_id and secret_key must both be set")
+	}
+	client.skipInactiveZones = p.SkipInactiveZones
+	client.records = newRecordCache(p.RecordCacheTTL)
+	if p.DomainCacheTTL != 0 {
+		client.domainCacheTTL = p.DomainCacheTTL
+	}
+	client.hedgeAfter = p.HedgeAfter
+	switch {
+	case p.MaxRetries < 0:
+		client.maxRetries = 0
+	case p.MaxRetries > 0:
+		client.maxRetries = p.MaxRetries
+	}
+	if p.RetryBackoff > 0 {
+		client.retryBackoff = p.RetryBackoff
+	}
+
+	if tokenErr != nil {
+		return client, fmt.Errorf("invalid login_token: %w", tokenErr)
+	}
+	if endpointErr != nil {
+		return client, fmt.Errorf("invalid endpoint: %w", endpointErr)
+	}
+	if secretIDErr != nil {
+		return client, fmt.Errorf("invalid secret_id: %w", secretIDErr)
+	}
+	if secretKeyErr != nil {
+		return client, fmt.Errorf("invalid secret_key: %w", secretKeyErr)
+	}
+
+	client.lineAliases = p.LineAliases
+	client.lineIDs = p.LineIDs
+	if p.DefaultLine != "" {
+		client.defaultLine = p.DefaultLine
+	}
+
+	switch strings.ToLower(p.Language) {
+	case "":
+	case "cn", "zh":
+		client.lang = "cn"
+	case "en":
+		client.lang = "en"
+	default:
+		return client, fmt.Errorf("unsupported language %q: use cn or en", p.Language)
+	}
+
+	mirrors := make([]string, 0, len(p.Mirrors))
+	for _, mirror := range p.Mirrors {
+		expanded, err := expandPlaceholders(mirror)
+		if err != nil {
+			return client, fmt.Errorf("invalid mirror: %w", err)
+		}
+		mirrors = append(mirrors, expanded)
+	}
+	if err := client.addMirrors(mirrors); err != nil {
+		return client, err
+	}
+	if err := client.setRateLimits(p.RateLimits, p.AdaptiveThrottle); err != nil {
+		return client, err
+	}
+
+	if useAPIv3 {
+		client.useAPIv3(secretID, secretKey)
+	}
+
+	return client, nil
+}
+
+// prepareRecord converts an input record to DNSPod format and validates it
+func (p *Provider) prepareRecord(client *Client, libRec libdns.Record, zone string) (record, error) {
+	if p.Strict {
+		if err := checkInputConversion(libRec); err != nil {
+			return record{}, err
+		}
+	}
+
+	if _, err := inputRemark(libRec); err != nil {
+		return record{}, err
+	}
+
+	rec := convertFromLibDNSRecord(libRec, zone)
+	if _, _, err := ParseWeight(rec.Weight); err != nil {
+		return record{}, err
+	}
+	if err := client.validateRecord(rec); err != nil {
+		return record{}, err
+	}
+
+	return rec, nil
+}
+
+// journal records a mutation if a journal is configured, and notifies the
+// configured webhooks and notifiers
+func (p *Provider) journal(ctx context.Context, zone string, op JournalOp, before, after []libdns.Record) error {
+	p.notify(zone, op, before, after)
+
+	if p.Journal == nil {
+		return nil
+	}
+	return p.Journal.record(ctx, zone, op, before, after)
+}
+
 // GetRecords lists all the records in the zone.
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
 	client := p.getClient()
@@ -41,17 +364,41 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 	}
 
 	// Convert to libdns format
-	var libRecords []libdns.Record
+	libRecords := make([]libdns.Record, 0, len(records))
 	for _, rec := range records {
+		if isSystemRecord(rec) && !p.IncludeSystemRecords {
+			continue
+		}
+		if p.Strict {
+			if err := checkOutputConversion(rec, zone); err != nil {
+				return nil, err
+			}
+		}
 		libRec := convertToLibDNSRecord(rec, zone)
 		libRecords = append(libRecords, libRec)
 	}
 
+	if p.IncludeSystemRecords {
+		if soa, ok := synthesizeSOA(records, zone); ok {
+			libRecords = append(libRecords, soa)
+		}
+	}
+
+	if len(libRecords) == 0 && p.ErrorOnEmpty {
+		return nil, fmt.Errorf("zone %s: %w", zone, ErrNoRecords)
+	}
+
 	return libRecords, nil
 }
 
 // AppendRecords adds records to the zone. It returns the records that were added.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	client := p.getClient()
 
 	// Get domain ID
@@ -60,28 +407,50 @@ func (p *Provider) AppendRecords(ctx context.Context, zone string, records []lib
 		return nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
 	}
 
-	var appendedRecords []libdns.Record
-
-	for _, libRec := range records {
-		// Convert to DNSPod format
-		rec := convertFromLibDNSRecord(libRec, zone)
-
-		// Create record
-		createdRec, err := client.createRecord(ctx, domainID, rec)
+	// Convert to DNSPod format
+	recs := make([]record, len(records))
+	for i, libRec := range records {
+		rec, err := p.prepareRecord(client, libRec, zone)
 		if err != nil {
-			return appendedRecords, fmt.Errorf("failed to create record %s: %w", libRec.RR().Name, err)
+			return nil, err
 		}
+		recs[i] = rec
+	}
 
+	var appendedRecords []libdns.Record
+	err = p.createRecords(ctx, client, zone, domainID, recs, func(_ int, createdRec *record) error {
 		// Convert back to libdns format
 		newLibRec := convertToLibDNSRecord(*createdRec, zone)
 		appendedRecords = append(appendedRecords, newLibRec)
+		return p.journal(ctx, zone, JournalAppend, nil, []libdns.Record{newLibRec})
+	})
+	return appendedRecords, err
+}
+
+// DeleteRecords deletes the records from the zone. Empty type, TTL and
+// value fields match any, and a line only needs to match if the record
+// sets one. Records that do not exist are ignored. It returns the records
+// that were deleted.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	changes, err := p.DeleteRecordsWithChanges(ctx, zone, records)
+
+	var deletedRecords []libdns.Record
+	for _, change := range changes {
+		deletedRecords = append(deletedRecords, change.Before)
 	}
 
-	return appendedRecords, nil
+	return deletedRecords, err
 }
 
-// DeleteRecords deletes the records from the zone.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+// DeleteRecordsWithChanges works like DeleteRecords, but returns one change
+// per deleted record whose Before holds the record as it was stored.
+func (p *Provider) DeleteRecordsWithChanges(ctx context.Context, zone string, records []libdns.Record) ([]Change, error) {
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
 	client := p.getClient()
 
 	// Get domain ID
@@ -90,50 +459,108 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 		return nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
 	}
 
-	// Get existing records to find IDs
-	existingRecords, err := client.listRecords(ctx, domainID)
+	// Get the existing records of the affected RRsets to find IDs
+	existingRecords, err := client.lookupRecords(ctx, domainID, zone, records)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list existing records: %w", err)
 	}
 
-	var deletedRecords []libdns.Record
+	var changes []Change
+	deleted := make([]bool, len(existingRecords))
 
 	for _, libRec := range records {
-		// Find matching record by name, type, and value
 		rr := libRec.RR()
-		var recordID string
+		want := convertFromLibDNSRecord(libRec, zone)
 
-		for _, existingRec := range existingRecords {
-			existingLibRec := convertToLibDNSRecord(existingRec, zone)
-			existingRR := existingLibRec.RR()
+		for i, existingRec := range existingRecords {
+			if deleted[i] || isSystemRecord(existingRec) || !matchesDelete(client, want, rr, existingRec) {
+				continue
+			}
 
-			if existingRR.Name == rr.Name &&
-				existingRR.Type == rr.Type &&
-				existingRR.Data == rr.Data {
-				recordID = existingRec.ID
-				break
+			// Delete record
+			existingLibRec := convertToLibDNSRecord(existingRec, zone)
+			if err := client.deleteRecord(ctx, domainID, existingRec.ID); err != nil {
+				return changes, fmt.Errorf("failed to delete record %s: %w", existingLibRec.RR().Name, err)
 			}
-		}
+			deleted[i] = true
 
-		if recordID == "" {
-			return deletedRecords, fmt.Errorf("record not found: %s %s %s", rr.Name, rr.Type, rr.Data)
-		}
+			changes = append(changes, Change{Op: ChangeDelete, Before: existingLibRec, existing: &existingRecords[i]})
 
-		// Delete record
-		err := client.deleteRecord(ctx, domainID, recordID)
-		if err != nil {
-			return deletedRecords, fmt.Errorf("failed to delete record %s: %w", rr.Name, err)
+			if err := p.journal(ctx, zone, JournalDelete, []libdns.Record{existingLibRec}, nil); err != nil {
+				return changes, err
+			}
 		}
-
-		deletedRecords = append(deletedRecords, libRec)
 	}
 
-	return deletedRecords, nil
+	return changes, nil
+}
+
+// matchesDelete reports whether an existing record is selected by a record
+// passed to DeleteRecords, rr being its generic form
+func matchesDelete(client *Client, want record, rr libdns.RR, existing record) bool {
+	if !strings.EqualFold(existing.Name, want.Name) {
+		return false
+	}
+	if rr.Type != "" && !strings.EqualFold(existing.Type, want.Type) {
+		return false
+	}
+	if rr.TTL != 0 && existing.TTL != want.TTL {
+		return false
+	}
+	if rr.Data != "" && !(sameRecordValue(want.Type, existing.Value, want.Value) &&
+		(!usesMX(want.Type) || existing.MX == want.MX)) {
+		return false
+	}
+	if want.Line != "" || want.LineID != "" {
+		return client.sameLine(want, existing)
+	}
+	return true
 }
 
 // SetRecords sets the records in the zone, either by updating existing records
-// or creating new ones. It returns the updated records.
+// or creating new ones. The records replace the RRsets (name, type and
+// line) they belong to: other existing records of those RRsets are
+// deleted. It returns the updated records.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	changes, err := p.SetRecordsWithChanges(ctx, zone, records)
+
+	var setRecords []libdns.Record
+	for _, change := range changes {
+		if change.Op != ChangeDelete {
+			setRecords = append(setRecords, change.After)
+		}
+	}
+
+	return setRecords, err
+}
+
+// SetRecordsWithChanges works like SetRecords, but returns one change per
+// input record, in order, followed by one delete per record removed from
+// the replaced RRsets. Updates carry the replaced record in Before, so
+// callers can log exactly what was overwritten or undo it.
+func (p *Provider) SetRecordsWithChanges(ctx context.Context, zone string, records []libdns.Record) ([]Change, error) {
+	if p.CoalesceWindow > 0 {
+		return p.coalesceSet(ctx, zone, records)
+	}
+	return p.setRecords(ctx, zone, records)
+}
+
+// setRecords sets records immediately. It returns one change per record,
+// in order, up to the first record that was not written, and the deletes
+// once all records were written.
+func (p *Provider) setRecords(ctx context.Context, zone string, records []libdns.Record) ([]Change, error) {
+	ctx, unlock, err := p.lockZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	unlockRRsets, err := p.lockRRsets(ctx, zone, records)
+	if err != nil {
+		return nil, err
+	}
+	defer unlockRRsets()
+
 	client := p.getClient()
 
 	// Get domain ID
@@ -142,53 +569,144 @@ func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns
 		return nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
 	}
 
-	// Get existing records to find IDs for updates
-	existingRecords, err := client.listRecords(ctx, domainID)
+	// Get the existing records of the affected RRsets to find IDs for
+	// updates
+	existingRecords, err := client.lookupRecords(ctx, domainID, zone, records)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list existing records: %w", err)
 	}
 
-	var setRecords []libdns.Record
-
-	for _, libRec := range records {
-		rr := libRec.RR()
-		var recordID string
+	recs := make([]record, len(records))
+	for i, libRec := range records {
+		rec, err := p.prepareRecord(client, libRec, zone)
+		if err != nil {
+			return nil, err
+		}
+		recs[i] = rec
+	}
 
-		// Check if record exists (match by name and type)
-		for _, existingRec := range existingRecords {
-			existingLibRec := convertToLibDNSRecord(existingRec, zone)
-			existingRR := existingLibRec.RR()
+	// The records replace the RRsets they belong to, by name, type and
+	// line. Match them against the existing records of their RRset first,
+	// so that updates and creates can each be batched: records with the
+	// same value first, then the others in order.
+	inRRset := func(rec, existing record) bool {
+		return !isSystemRecord(existing) &&
+			strings.EqualFold(existing.Name, rec.Name) &&
+			strings.EqualFold(existing.Type, rec.Type) &&
+			client.sameLine(rec, existing)
+	}
 
-			if existingRR.Name == rr.Name && existingRR.Type == rr.Type {
-				recordID = existingRec.ID
+	applied := make([]*Change, len(records))
+	matched := make([]*record, len(records))
+	previous := make([]libdns.Record, len(records))
+	claimed := make([]bool, len(existingRecords))
+	for _, sameData := range []bool{true, false} {
+		for i, rec := range recs {
+			if matched[i] != nil {
+				continue
+			}
+			for j, existingRec := range existingRecords {
+				if claimed[j] || !inRRset(rec, existingRec) {
+					continue
+				}
+				if sameData && !(sameRecordValue(rec.Type, existingRec.Value, rec.Value) &&
+					(!usesMX(rec.Type) || existingRec.MX == rec.MX)) {
+					continue
+				}
+				claimed[j] = true
+				matched[i] = &existingRecords[j]
+				previous[i] = convertToLibDNSRecord(existingRec, zone)
 				break
 			}
 		}
+	}
 
-		rec := convertFromLibDNSRecord(libRec, zone)
-
-		if recordID != "" {
-			// Update existing record
-			updatedRec, err := client.updateRecord(ctx, domainID, recordID, rec)
-			if err != nil {
-				return setRecords, fmt.Errorf("failed to update record %s: %w", rr.Name, err)
+	// Existing records of the RRsets that no record took over are deleted
+	var stale []int
+	for j, existingRec := range existingRecords {
+		if claimed[j] {
+			continue
+		}
+		for _, rec := range recs {
+			if inRRset(rec, existingRec) {
+				stale = append(stale, j)
+				break
 			}
+		}
+	}
 
-			newLibRec := convertToLibDNSRecord(*updatedRec, zone)
-			setRecords = append(setRecords, newLibRec)
-		} else {
-			// Create new record
-			createdRec, err := client.createRecord(ctx, domainID, rec)
-			if err != nil {
-				return setRecords, fmt.Errorf("failed to create record %s: %w", rr.Name, err)
+	// changes returns one change per record, in order, up to the first
+	// record that was not written
+	changes := func() []Change {
+		var changes []Change
+		for _, change := range applied {
+			if change == nil {
+				break
 			}
+			changes = append(changes, *change)
+		}
+		return changes
+	}
+
+	updated := func(i int, updatedRec *record) error {
+		newLibRec := convertToLibDNSRecord(*updatedRec, zone)
+		applied[i] = &Change{Op: ChangeUpdate, Before: previous[i], After: newLibRec, existing: updatedRec}
+		return p.journal(ctx, zone, JournalSet, []libdns.Record{previous[i]}, []libdns.Record{newLibRec})
+	}
 
-			newLibRec := convertToLibDNSRecord(*createdRec, zone)
-			setRecords = append(setRecords, newLibRec)
+	// Update existing records, in batches of updates that change the same
+	// field to the same value
+	var updates []int
+	var updateExisting, updateRecs []record
+	for i, existing := range matched {
+		if existing != nil {
+			updates = append(updates, i)
+			updateExisting = append(updateExisting, *existing)
+			updateRecs = append(updateRecs, recs[i])
 		}
 	}
+	err = p.updateRecords(ctx, client, zone, domainID, updateExisting, updateRecs, func(j int, updatedRec *record) error {
+		return updated(updates[j], updatedRec)
+	})
+	if err != nil {
+		return changes(), err
+	}
 
-	return setRecords, nil
+	// Create new records
+	var creates []int
+	var createRecs []record
+	for i, existing := range matched {
+		if existing == nil {
+			creates = append(creates, i)
+			createRecs = append(createRecs, recs[i])
+		}
+	}
+	err = p.createRecords(ctx, client, zone, domainID, createRecs, func(j int, createdRec *record) error {
+		i := creates[j]
+		newLibRec := convertToLibDNSRecord(*createdRec, zone)
+		applied[i] = &Change{Op: ChangeCreate, After: newLibRec, existing: createdRec}
+		return p.journal(ctx, zone, JournalSet, nil, []libdns.Record{newLibRec})
+	})
+	if err != nil {
+		return changes(), err
+	}
+
+	// Delete the rest of the replaced RRsets last, so that they are never
+	// empty in between
+	result := changes()
+	for _, j := range stale {
+		staleRec := &existingRecords[j]
+		before := convertToLibDNSRecord(*staleRec, zone)
+		if err := client.deleteRecord(ctx, domainID, staleRec.ID); err != nil {
+			return result, fmt.Errorf("failed to delete record %s: %w", before.RR().Name, err)
+		}
+
+		result = append(result, Change{Op: ChangeDelete, Before: before, existing: staleRec})
+		if err := p.journal(ctx, zone, JournalDelete, []libdns.Record{before}, nil); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
 }
 
 // Interface guards
@@ -197,4 +715,5 @@ var (
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
@@ -0,0 +1,86 @@
+package dnspod
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestConfigErrorFailsRequests(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	p := &Provider{LoginToken: "{env.DNSPOD_TEST_UNSET_TOKEN}", Endpoint: server.URL}
+	for i := 0; i < 2; i++ {
+		_, err := p.GetRecords(context.Background(), "example.com.")
+		if err == nil || !strings.Contains(err.Error(), "DNSPOD_TEST_UNSET_TOKEN") {
+			t.Errorf("call %d: got error %v, want the configuration error", i, err)
+		}
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("sent %d requests with an invalid configuration", n)
+	}
+}
+
+func TestIncompleteSecretsFailRequests(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		http.NotFound(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	tests := []struct {
+		name      string
+		secretID  string
+		secretKey string
+	}{
+		{"only id", "AKID", ""},
+		{"only key", "", "key"},
+		{"unresolved key", "AKID", "{env.DNSPOD_TEST_UNSET_KEY}"},
+		{"empty file", "{file./dev/null}", "key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{SecretID: tt.secretID, SecretKey: tt.secretKey, Endpoint: server.URL}
+			if err := p.Provision(); err == nil {
+				t.Error("Provision succeeded")
+			}
+			if _, err := p.GetRecords(context.Background(), "example.com."); err == nil {
+				t.Error("GetRecords succeeded")
+			}
+		})
+	}
+	if n := hits.Load(); n != 0 {
+		t.Errorf("sent %d requests without both keys", n)
+	}
+}
+
+func TestGetClientConcurrent(t *testing.T) {
+	p := &Provider{LoginToken: "1,token"}
+
+	clients := make([]*Client, 16)
+	var wg sync.WaitGroup
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			clients[i] = p.getClient()
+		}()
+	}
+	wg.Wait()
+
+	for i, c := range clients {
+		if c != clients[0] {
+			t.Fatalf("call %d got a different client", i)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultRateLimitKey configures the limit for actions not listed
+	// individually in Provider.RateLimits
+	defaultRateLimitKey = "*"
+
+	// frequencyLimitCode is the API status code for exceeding the
+	// account's request limit
+	frequencyLimitCode = "-2"
+
+	// defaultAdaptiveRate is the starting rate for adaptive throttling of
+	// actions without a configured limit
+	defaultAdaptiveRate = 10
+
+	// minAdaptiveRate is the floor adaptive throttling backs off to
+	minAdaptiveRate = 0.1
+
+	// adaptiveRecoverySteps is the number of successful requests it takes
+	// to recover from the floor to the configured rate
+	adaptiveRecoverySteps = 20
+)
+
+// rateLimiter is a token bucket allowing rate requests per second, with
+// bursts of up to one second's worth. With adaptive throttling, rate drops
+// below base after frequency-limit errors and recovers on success.
+type rateLimiter struct {
+	mutex  sync.Mutex
+	base   float64
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter with a full bucket
+func newRateLimiter(rate float64) *rateLimiter {
+	return &rateLimiter{base: rate, rate: rate, tokens: burstFor(rate), last: time.Now()}
+}
+
+// backoff halves the rate after a frequency-limit error
+func (l *rateLimiter) backoff() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rate /= 2
+	if l.rate < minAdaptiveRate {
+		l.rate = minAdaptiveRate
+	}
+}
+
+// recover moves the rate back towards base after a successful request
+func (l *rateLimiter) recover() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.rate += l.base / adaptiveRecoverySteps
+	if l.rate > l.base {
+		l.rate = l.base
+	}
+}
+
+// currentRate returns the effective rate
+func (l *rateLimiter) currentRate() float64 {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.rate
+}
+
+// burstFor returns the bucket size for a rate
+func burstFor(rate float64) float64 {
+	if rate < 1 {
+		return 1
+	}
+	return rate
+}
+
+// wait blocks until a request may be sent
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mutex.Lock()
+		now := time.Now()
+		l.tokens += now.Sub(l.last).Seconds() * l.rate
+		if burst := burstFor(l.rate); l.tokens > burst {
+			l.tokens = burst
+		}
+		l.last = now
+
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mutex.Unlock()
+			return nil
+		}
+		delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mutex.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// setRateLimits creates a limiter for each configured action. Rates are in
+// requests per second; the "*" entry applies to every other action. With
+// adaptive throttling, a "*" limiter is added if none is configured.
+func (c *Client) setRateLimits(limits map[string]float64, adaptive bool) error {
+	c.adaptive = adaptive
+	if len(limits) == 0 && !adaptive {
+		return nil
+	}
+
+	c.limiters = make(map[string]*rateLimiter, len(limits)+1)
+	for action, rate := range limits {
+		if rate <= 0 {
+			return fmt.Errorf("invalid rate limit %v for %s: must be positive", rate, action)
+		}
+		c.limiters[action] = newRateLimiter(rate)
+	}
+	if _, ok := c.limiters[defaultRateLimitKey]; adaptive && !ok {
+		c.limiters[defaultRateLimitKey] = newRateLimiter(defaultAdaptiveRate)
+	}
+	return nil
+}
+
+// limiter returns the limiter for an action, or nil if it is unlimited
+func (c *Client) limiter(action string) *rateLimiter {
+	if l, ok := c.limiters[action]; ok {
+		return l
+	}
+	return c.limiters[defaultRateLimitKey]
+}
+
+// observe records the outcome of a request and, with adaptive throttling,
+// adjusts the rate of its action
+func (c *Client) observe(action, code string) {
+	c.requests.Add(1)
+	if code == frequencyLimitCode {
+		c.rateLimited.Add(1)
+	}
+
+	if !c.adaptive {
+		return
+	}
+	l := c.limiter(action)
+	if l == nil {
+		return
+	}
+	if code == frequencyLimitCode {
+		l.backoff()
+	} else {
+		l.recover()
+	}
+}
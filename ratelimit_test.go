@@ -0,0 +1,108 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterWait(t *testing.T) {
+	l := newRateLimiter(20)
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		if err := l.wait(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 40*time.Millisecond {
+		t.Errorf("a full bucket took %v", elapsed)
+	}
+
+	start = time.Now()
+	if err := l.wait(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("an empty bucket let a request through after %v", elapsed)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	l.tokens = 0
+	if err := l.wait(canceled); err != context.Canceled {
+		t.Errorf("got %v on a canceled context", err)
+	}
+}
+
+func TestRateLimiterAdaptive(t *testing.T) {
+	l := newRateLimiter(1)
+	l.backoff()
+	if rate := l.currentRate(); rate != 0.5 {
+		t.Errorf("rate %v after a backoff, want 0.5", rate)
+	}
+	for i := 0; i < 10; i++ {
+		l.backoff()
+	}
+	if rate := l.currentRate(); rate != minAdaptiveRate {
+		t.Errorf("rate %v after many backoffs, want %v", rate, minAdaptiveRate)
+	}
+	for i := 0; i < adaptiveRecoverySteps; i++ {
+		l.recover()
+	}
+	if rate := l.currentRate(); rate != 1 {
+		t.Errorf("rate %v after recovering, want 1", rate)
+	}
+}
+
+func TestSetRateLimits(t *testing.T) {
+	c := newClient("1,token", "")
+	if err := c.setRateLimits(map[string]float64{"Record.List": 0}, false); err == nil {
+		t.Error("accepted a zero rate")
+	}
+
+	c = newClient("1,token", "")
+	if err := c.setRateLimits(map[string]float64{"Record.List": 5}, true); err != nil {
+		t.Fatal(err)
+	}
+	if l := c.limiter("Record.List"); l == nil || l.currentRate() != 5 {
+		t.Errorf("Record.List limiter %+v, want 5 per second", l)
+	}
+	if l := c.limiter("Record.Create"); l == nil || l.currentRate() != defaultAdaptiveRate {
+		t.Errorf("default limiter %+v, want %v per second", l, float64(defaultAdaptiveRate))
+	}
+}
+
+func TestAdaptiveThrottle(t *testing.T) {
+	var hits atomic.Int64
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		status := map[string]string{"code": "1", "message": "ok"}
+		if hits.Add(1) == 1 {
+			status = map[string]string{"code": frequencyLimitCode, "message": "API usage exceeded"}
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"status":  status,
+			"info":    map[string]any{"domain_total": 0},
+			"domains": []any{},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	p := &Provider{LoginToken: "1,token", Endpoint: server.URL, AdaptiveThrottle: true, RetryBackoff: time.Millisecond}
+	if _, err := p.ListZones(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := p.Stats()
+	if stats.Requests != 2 || stats.RateLimited != 1 || stats.Retries != 1 {
+		t.Errorf("got %d requests, %d rate limited, %d retries", stats.Requests, stats.RateLimited, stats.Retries)
+	}
+	if rate := stats.Rates[defaultRateLimitKey]; rate >= defaultAdaptiveRate || rate <= defaultAdaptiveRate/2 {
+		t.Errorf("rate %v, want a partial recovery from %v", rate, defaultAdaptiveRate/2.0)
+	}
+}
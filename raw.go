@@ -0,0 +1,61 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libdns/libdns"
+)
+
+// RawRecord is a record exactly as DNSPod returns it from Record.List, for
+// fields the libdns model cannot express such as the resolution line,
+// weight and remark. All values are strings as in the API.
+type RawRecord struct {
+	ID        string `json:"id"`
+	TTL       string `json:"ttl"`
+	Value     string `json:"value"`
+	Enabled   string `json:"enabled"`
+	Status    string `json:"status"`
+	UpdatedOn string `json:"updated_on"`
+
+	// Name is relative to the zone, "@" for the apex
+	Name   string `json:"name"`
+	Line   string `json:"line"`
+	LineID string `json:"line_id"`
+	Type   string `json:"type"`
+	Weight string `json:"weight,omitempty"`
+	MX     string `json:"mx,omitempty"`
+	Remark string `json:"remark,omitempty"`
+}
+
+// GetRawRecords lists the records in the zone both as DNSPod returns them
+// and converted to libdns records; raw[i] corresponds to records[i]. System
+// records are included as configured by IncludeSystemRecords, but the
+// synthesized SOA record is not, since it has no raw form.
+func (p *Provider) GetRawRecords(ctx context.Context, zone string) (raw []RawRecord, records []libdns.Record, err error) {
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw = make([]RawRecord, 0, len(existingRecords))
+	records = make([]libdns.Record, 0, len(existingRecords))
+	for _, rec := range existingRecords {
+		if isSystemRecord(rec) && !p.IncludeSystemRecords {
+			continue
+		}
+		if p.Strict {
+			if err := checkOutputConversion(rec, zone); err != nil {
+				return nil, nil, err
+			}
+		}
+		raw = append(raw, RawRecord(rec))
+		records = append(records, convertToLibDNSRecord(rec, zone))
+	}
+
+	if len(raw) == 0 && p.ErrorOnEmpty {
+		return nil, nil, fmt.Errorf("zone %s: %w", zone, ErrNoRecords)
+	}
+
+	return raw, records, nil
+}
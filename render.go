@@ -0,0 +1,167 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// changeJSON is the serialized form of a Change
+type changeJSON struct {
+	Op     ChangeOp   `json:"op"`
+	Before *libdns.RR `json:"before,omitempty"`
+	After  *libdns.RR `json:"after,omitempty"`
+}
+
+// planJSON is the serialized form of a Plan
+type planJSON struct {
+	Zone    string       `json:"zone"`
+	Summary planSummary  `json:"summary"`
+	Changes []changeJSON `json:"changes"`
+}
+
+type planSummary struct {
+	Create int `json:"create"`
+	Update int `json:"update"`
+	Delete int `json:"delete"`
+}
+
+// MarshalJSON implements json.Marshaler. Records are serialized in their
+// RR form.
+func (c Change) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.toJSON())
+}
+
+func (c Change) toJSON() changeJSON {
+	out := changeJSON{Op: c.Op}
+	if c.Before != nil {
+		rr := c.Before.RR()
+		out.Before = &rr
+	}
+	if c.After != nil {
+		rr := c.After.RR()
+		out.After = &rr
+	}
+	return out
+}
+
+// String renders the change as a single line: "+" for creates, "~" for
+// updates and "-" for deletes
+func (c Change) String() string {
+	switch c.Op {
+	case ChangeCreate:
+		return "+ " + formatRR(c.After)
+	case ChangeDelete:
+		return "- " + formatRR(c.Before)
+	case ChangeUpdate:
+		return "~ " + formatRR(c.Before) + " => " + formatRR(c.After)
+	default:
+		return fmt.Sprintf("? %s", c.Op)
+	}
+}
+
+// summary counts the changes by operation
+func (pl *Plan) summary() planSummary {
+	var s planSummary
+	if pl == nil {
+		return s
+	}
+	for _, c := range pl.Changes {
+		switch c.Op {
+		case ChangeCreate:
+			s.Create++
+		case ChangeUpdate:
+			s.Update++
+		case ChangeDelete:
+			s.Delete++
+		}
+	}
+	return s
+}
+
+// MarshalJSON implements json.Marshaler. A nil plan is serialized as an
+// empty one.
+func (pl *Plan) MarshalJSON() ([]byte, error) {
+	out := planJSON{Changes: []changeJSON{}}
+	if pl == nil {
+		return json.Marshal(out)
+	}
+
+	out.Zone = pl.Zone
+	out.Summary = pl.summary()
+	for _, c := range pl.Changes {
+		out.Changes = append(out.Changes, c.toJSON())
+	}
+	return json.Marshal(out)
+}
+
+// String renders the plan for a terminal, one change per line after a
+// summary line
+func (pl *Plan) String() string {
+	if pl == nil {
+		return "no changes"
+	}
+
+	s := pl.summary()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Plan for %s: %d to create, %d to update, %d to delete\n", pl.Zone, s.Create, s.Update, s.Delete)
+	for _, c := range pl.Changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Markdown renders the plan as a Markdown table, suitable for posting as a
+// pull request comment
+func (pl *Plan) Markdown() string {
+	if pl.Empty() {
+		return "No changes.\n"
+	}
+
+	s := pl.summary()
+	var b strings.Builder
+	fmt.Fprintf(&b, "### DNS plan for `%s`\n\n", pl.Zone)
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to delete\n\n", s.Create, s.Update, s.Delete)
+	b.WriteString("| Op | Name | Type | Before | After |\n")
+	b.WriteString("|----|------|------|--------|-------|\n")
+
+	for _, c := range pl.Changes {
+		var name, typ, before, after string
+		if c.Before != nil {
+			rr := c.Before.RR()
+			name, typ = rr.Name, rr.Type
+			before = fmt.Sprintf("%s (TTL %d)", rr.Data, int(rr.TTL.Seconds()))
+		}
+		if c.After != nil {
+			rr := c.After.RR()
+			name, typ = rr.Name, rr.Type
+			after = fmt.Sprintf("%s (TTL %d)", rr.Data, int(rr.TTL.Seconds()))
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | %s |\n",
+			c.Op, name, typ, markdownCell(before), markdownCell(after))
+	}
+
+	return b.String()
+}
+
+// formatRR renders a record in zone file order: name, TTL, type, data
+func formatRR(rec libdns.Record) string {
+	if rec == nil {
+		return "<none>"
+	}
+	rr := rec.RR()
+	return fmt.Sprintf("%s %d %s %s", rr.Name, int(rr.TTL.Seconds()), rr.Type, rr.Data)
+}
+
+// markdownCell escapes a value for use inside a table cell
+func markdownCell(s string) string {
+	if s == "" {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "|", `\|`)
+	s = strings.ReplaceAll(s, "\n", " ")
+	return "`" + strings.ReplaceAll(s, "`", "'") + "`"
+}
@@ -0,0 +1,49 @@
+package dnspod
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestRenderNilPlan(t *testing.T) {
+	var plan *Plan
+
+	data, err := plan.MarshalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"zone":"","summary":{"create":0,"update":0,"delete":0},"changes":[]}`; string(data) != want {
+		t.Errorf("got JSON %s, want %s", data, want)
+	}
+	if s := plan.String(); s != "no changes" {
+		t.Errorf("got string %q", s)
+	}
+	if s := plan.Markdown(); s != "No changes.\n" {
+		t.Errorf("got Markdown %q", s)
+	}
+}
+
+func TestPlanJSON(t *testing.T) {
+	plan := &Plan{Zone: "example.com.", Changes: []Change{
+		{Op: ChangeCreate, After: libdns.TXT{Name: "a.example.com.", TTL: time.Minute, Text: "x"}},
+		{Op: ChangeDelete, Before: libdns.TXT{Name: "b.example.com.", TTL: time.Minute, Text: "y"}},
+	}}
+
+	data, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out planJSON
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Zone != "example.com." || out.Summary != (planSummary{Create: 1, Delete: 1}) || len(out.Changes) != 2 {
+		t.Fatalf("got %s", data)
+	}
+	if out.Changes[0].After == nil || out.Changes[0].After.Data != "x" || out.Changes[1].Before == nil || out.Changes[1].Before.Name != "b.example.com." {
+		t.Errorf("got changes %s", data)
+	}
+}
@@ -0,0 +1,101 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is how often a failed request is retried by
+	// default
+	defaultMaxRetries = 3
+
+	// defaultRetryBackoff is the delay before the first retry by default;
+	// it doubles with every further retry
+	defaultRetryBackoff = time.Second
+
+	// maxRetryBackoff caps the delay between retries
+	maxRetryBackoff = 30 * time.Second
+
+	// unknownErrorCode is the API status code for an internal error
+	unknownErrorCode = "3"
+)
+
+// readActions are the API actions that change nothing, and may be sent
+// again after any failure
+var readActions = map[string]bool{
+	"Domain.List":  true,
+	"Domain.Info":  true,
+	"Record.List":  true,
+	"Record.Info":  true,
+	"Info.Version": true,
+	"Batch.Detail": true,
+}
+
+// retryable reports whether a failed request may be sent again. Frequency
+// limits mean the request was rejected and connection errors that it was
+// never sent, so those are retried for every action. Server errors,
+// internal API errors and network failures such as timeouts and closed or
+// reset connections are only retried for reads, since a write may have been
+// applied before the failure. Anything else, such as an unparseable
+// response or a canceled context, is returned at once.
+func retryable(action string, err error) bool {
+	if IsRateLimited(err) || isConnectError(err) {
+		return true
+	}
+	if !readActions[action] {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == unknownErrorCode || strings.HasPrefix(apiErr.Code, "InternalError")
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500
+	}
+	return isNetworkError(err)
+}
+
+// isNetworkError reports whether err is a network failure or timeout
+// while the request or response was in flight. The *url.Error that wraps
+// every failure of an HTTP request is looked through, so that, for example,
+// certificate errors are not retried.
+func isNetworkError(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET)
+}
+
+// retryDelay returns the delay before a retry: the backoff doubled for each
+// earlier attempt, capped, of which a random part is taken so that clients
+// do not retry in lockstep
+func (c *Client) retryDelay(attempt int) time.Duration {
+	delay := c.retryBackoff
+	for i := 0; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxRetryBackoff {
+		delay = maxRetryBackoff
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay/2 + rand.N(delay/2+1)
+}
@@ -0,0 +1,89 @@
+package dnspod
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryable(t *testing.T) {
+	tests := []struct {
+		name   string
+		action string
+		err    error
+		want   bool
+	}{
+		{"rate limited write", "Record.Create", &APIError{Code: frequencyLimitCode}, true},
+		{"dial error on a write", "Record.Create", &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}, true},
+		{"reset on a write", "Record.Create", &net.OpError{Op: "read", Err: syscall.ECONNRESET}, false},
+		{"reset on a read", "Record.List", fmt.Errorf("failed to make request: %w", &url.Error{Op: "Post", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}), true},
+		{"timeout on a read", "Record.List", &url.Error{Op: "Post", Err: os.ErrDeadlineExceeded}, true},
+		{"truncated read", "Domain.List", fmt.Errorf("failed to read response: %w", io.ErrUnexpectedEOF), true},
+		{"internal API error on a read", "Record.List", &APIError{Code: unknownErrorCode}, true},
+		{"server error on a read", "Record.List", &HTTPError{StatusCode: http.StatusBadGateway}, true},
+		{"not found on a read", "Record.List", &HTTPError{StatusCode: http.StatusNotFound}, false},
+		{"unparseable response", "Record.List", fmt.Errorf("failed to parse response: %w", &json.SyntaxError{}), false},
+		{"certificate error", "Record.List", &url.Error{Op: "Post", Err: x509.UnknownAuthorityError{}}, false},
+		{"canceled read", "Record.List", &url.Error{Op: "Post", Err: context.Canceled}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.action, tt.err); got != tt.want {
+				t.Errorf("retryable(%s, %v) = %v, want %v", tt.action, tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryReads(t *testing.T) {
+	tests := []struct {
+		name  string
+		fail  func(w http.ResponseWriter)
+		sends int64
+	}{
+		{"dropped connection", func(w http.ResponseWriter) {
+			conn, _, _ := w.(http.Hijacker).Hijack()
+			conn.Close()
+		}, 2},
+		{"unparseable response", func(w http.ResponseWriter) {
+			io.WriteString(w, "<html>")
+		}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits atomic.Int64
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if hits.Add(1) == 1 {
+					tt.fail(w)
+					return
+				}
+				json.NewEncoder(w).Encode(map[string]any{
+					"status":  map[string]string{"code": "1", "message": "ok"},
+					"info":    map[string]any{"domain_total": 0},
+					"domains": []any{},
+				})
+			}))
+			t.Cleanup(server.Close)
+
+			p := &Provider{LoginToken: "1,token", Endpoint: server.URL, RetryBackoff: time.Millisecond}
+			_, err := p.ListZones(context.Background())
+			if n := hits.Load(); n != tt.sends {
+				t.Errorf("sent %d requests, want %d (error %v)", n, tt.sends, err)
+			}
+			if (err == nil) != (tt.sends > 1) {
+				t.Errorf("got error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package dnspod
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// srvTransports lists the transport labels accepted in SRV owner names
+var srvTransports = map[string]bool{
+	"tcp":  true,
+	"udp":  true,
+	"tls":  true,
+	"sctp": true,
+	"dccp": true,
+}
+
+// NewSRV builds an SRV record for _service._proto.name within zone,
+// validating the fields against DNSPod's requirements. Name may be "@" or
+// empty for the zone apex, a relative subdomain, or an absolute name.
+func NewSRV(zone, service, proto, name string, priority, weight, port uint16, target string, ttl time.Duration) (libdns.SRV, error) {
+	service = strings.TrimPrefix(service, "_")
+	proto = strings.ToLower(strings.TrimPrefix(proto, "_"))
+
+	if err := validateSRVService(service); err != nil {
+		return libdns.SRV{}, err
+	}
+	if err := validateSRVTransport(proto); err != nil {
+		return libdns.SRV{}, err
+	}
+	if err := validateSRVTarget(target); err != nil {
+		return libdns.SRV{}, err
+	}
+
+	return libdns.SRV{
+		Service:   service,
+		Transport: proto,
+		Name:      makeAbsoluteName(extractRecordName(name, zone), zone),
+		TTL:       ttl,
+		Priority:  priority,
+		Weight:    weight,
+		Port:      port,
+		Target:    target,
+	}, nil
+}
+
+// validateSRV checks that a DNSPod SRV record has a _service._proto name,
+// a "weight port target" value and the priority in the mx field
+func validateSRV(rec record) error {
+	labels := strings.Split(rec.Name, ".")
+	if len(labels) < 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+		return fmt.Errorf("invalid SRV record name %q: must start with _service._proto", rec.Name)
+	}
+	if err := validateSRVService(strings.TrimPrefix(labels[0], "_")); err != nil {
+		return err
+	}
+	if err := validateSRVTransport(strings.ToLower(strings.TrimPrefix(labels[1], "_"))); err != nil {
+		return err
+	}
+
+	if _, err := strconv.ParseUint(rec.MX, 10, 16); err != nil {
+		return fmt.Errorf("invalid SRV record priority %q: must be between 0 and 65535", rec.MX)
+	}
+
+	fields := strings.Fields(rec.Value)
+	if len(fields) != 3 {
+		return fmt.Errorf("invalid SRV record value %q: expected \"weight port target\"", rec.Value)
+	}
+	for i, field := range []string{"weight", "port"} {
+		if _, err := strconv.ParseUint(fields[i], 10, 16); err != nil {
+			return fmt.Errorf("invalid SRV record value %q: %s must be between 0 and 65535", rec.Value, field)
+		}
+	}
+
+	return validateSRVTarget(fields[2])
+}
+
+// validateSRVService checks the service label (without underscore)
+func validateSRVService(service string) error {
+	if service == "" || len(service) > 15 {
+		return fmt.Errorf("invalid SRV service %q: must be 1-15 characters", service)
+	}
+	for _, c := range service {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return fmt.Errorf("invalid SRV service %q: only letters, digits and hyphens are allowed", service)
+		}
+	}
+	if strings.HasPrefix(service, "-") || strings.HasSuffix(service, "-") {
+		return fmt.Errorf("invalid SRV service %q: must not start or end with a hyphen", service)
+	}
+	return nil
+}
+
+// validateSRVTransport checks the transport label (without underscore)
+func validateSRVTransport(proto string) error {
+	if !srvTransports[proto] {
+		return fmt.Errorf("invalid SRV transport %q: expected one of tcp, udp, tls, sctp, dccp", proto)
+	}
+	return nil
+}
+
+// validateSRVTarget checks the target host name. A single "." means the
+// service is explicitly not available.
+func validateSRVTarget(target string) error {
+	if target == "." {
+		return nil
+	}
+	host := strings.TrimSuffix(target, ".")
+	if host == "" {
+		return fmt.Errorf("invalid SRV target %q: host name is required", target)
+	}
+	for _, label := range strings.Split(host, ".") {
+		if label == "" || len(label) > 63 {
+			return fmt.Errorf("invalid SRV target %q: malformed host name", target)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/miekg/dns"
+)
+
+// defaultStandbyInterval is how often StandbySync.Run copies the zone
+const defaultStandbyInterval = 15 * time.Minute
+
+// StandbyTarget receives copies of a zone
+type StandbyTarget interface {
+	// SyncZone replaces the copy of zone with records. Record names are
+	// fully qualified.
+	SyncZone(ctx context.Context, zone string, records []libdns.Record) error
+}
+
+// StandbySync keeps a warm copy of a DNSPod zone elsewhere, such as at a
+// second DNS provider or in a zone file served by a standby name server,
+// for disaster recovery. System records and disabled records are not
+// copied.
+type StandbySync struct {
+	Provider *Provider
+	Zone     string
+	Target   StandbyTarget
+
+	// Interval is how often Run copies the zone. It defaults to 15
+	// minutes.
+	Interval time.Duration
+
+	mutex    sync.Mutex
+	lastSync time.Time
+	lastErr  error
+}
+
+// Run copies the zone every Interval until ctx is done. Errors are reported
+// through the provider's warning handler and retried on the next run.
+func (s *StandbySync) Run(ctx context.Context) error {
+	if s.Target == nil {
+		return errors.New("standby sync has no target")
+	}
+
+	interval := s.Interval
+	if interval <= 0 {
+		interval = defaultStandbyInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.SyncOnce(ctx); err != nil {
+			s.Provider.warn(err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// SyncOnce exports the zone from DNSPod and pushes it to the target
+func (s *StandbySync) SyncOnce(ctx context.Context) error {
+	err := s.sync(ctx)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.lastErr = err
+	if err == nil {
+		s.lastSync = time.Now()
+	}
+	return err
+}
+
+// LastSync returns when the zone was last copied successfully, or the zero
+// time if it never was
+func (s *StandbySync) LastSync() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastSync
+}
+
+// LastError returns the error of the last copy, or nil if it succeeded
+func (s *StandbySync) LastError() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastErr
+}
+
+// sync makes a single copy of the zone
+func (s *StandbySync) sync(ctx context.Context) error {
+	if s.Target == nil {
+		return errors.New("standby sync has no target")
+	}
+
+	_, _, existingRecords, err := s.Provider.listZoneRecords(ctx, s.Zone)
+	if err != nil {
+		return err
+	}
+
+	records := make([]libdns.Record, 0, len(existingRecords))
+	for _, rec := range existingRecords {
+		if isSystemRecord(rec) {
+			continue
+		}
+		if enabled, err := ParseEnabled(rec.Enabled); err == nil && !enabled {
+			continue
+		}
+		records = append(records, convertToLibDNSRecord(rec, s.Zone))
+	}
+
+	if err := s.Target.SyncZone(ctx, s.Zone, records); err != nil {
+		return fmt.Errorf("failed to sync zone %s to standby: %w", s.Zone, err)
+	}
+	return nil
+}
+
+// StandbyProvider is the set of libdns interfaces a standby provider must
+// implement
+type StandbyProvider interface {
+	libdns.RecordGetter
+	libdns.RecordSetter
+	libdns.RecordDeleter
+}
+
+// ProviderTarget copies zones to another libdns provider. The RRsets of the
+// copy are replaced with SetRecords.
+type ProviderTarget struct {
+	Provider StandbyProvider
+
+	// Prune deletes RRsets at the standby that are no longer in the zone.
+	// The standby's own SOA and apex NS records are always kept.
+	Prune bool
+}
+
+// SyncZone implements StandbyTarget
+func (t *ProviderTarget) SyncZone(ctx context.Context, zone string, records []libdns.Record) error {
+	copies := make([]libdns.Record, 0, len(records))
+	keep := make(map[rrsetKey]bool, len(records))
+	for _, rec := range records {
+		rr := rec.RR()
+		rr.Name = libdns.RelativeName(rr.Name, zone)
+		parsed, err := rr.Parse()
+		if err != nil {
+			return fmt.Errorf("failed to convert record %s: %w", rr.Name, err)
+		}
+		copies = append(copies, parsed)
+		keep[rrsetKey{name: strings.ToLower(rr.Name), typ: strings.ToUpper(rr.Type)}] = true
+	}
+
+	if len(copies) > 0 {
+		if _, err := t.Provider.SetRecords(ctx, zone, copies); err != nil {
+			return fmt.Errorf("failed to set standby records: %w", err)
+		}
+	}
+
+	if !t.Prune {
+		return nil
+	}
+
+	existing, err := t.Provider.GetRecords(ctx, zone)
+	if err != nil {
+		return fmt.Errorf("failed to list standby records: %w", err)
+	}
+
+	var stale []libdns.Record
+	for _, rec := range existing {
+		rr := rec.RR()
+		name := strings.ToLower(libdns.RelativeName(rr.Name, zone))
+		typ := strings.ToUpper(rr.Type)
+		if typ == "SOA" || (typ == "NS" && name == "@") {
+			continue
+		}
+		if !keep[rrsetKey{name: name, typ: typ}] {
+			stale = append(stale, rec)
+		}
+	}
+
+	if len(stale) > 0 {
+		if _, err := t.Provider.DeleteRecords(ctx, zone, stale); err != nil {
+			return fmt.Errorf("failed to delete stale standby records: %w", err)
+		}
+	}
+	return nil
+}
+
+// ZoneFileTarget writes zones to an RFC 1035 zone file, replacing it
+// atomically. The file has no SOA or apex NS records; the standby name
+// server is expected to add its own, e.g. with an $INCLUDE.
+type ZoneFileTarget struct {
+	Path string
+}
+
+// SyncZone implements StandbyTarget
+func (t *ZoneFileTarget) SyncZone(ctx context.Context, zone string, records []libdns.Record) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "; %s exported from DNSPod at %s\n", dns.Fqdn(zone), time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintf(&b, "$ORIGIN %s\n", dns.Fqdn(zone))
+
+	for _, rec := range records {
+		rr, err := zoneFileRR(rec, zone)
+		if err != nil {
+			return err
+		}
+		b.WriteString(rr.String())
+		b.WriteByte('\n')
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(t.Path), filepath.Base(t.Path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.WriteString(b.String()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), t.Path); err != nil {
+		return fmt.Errorf("failed to write zone file: %w", err)
+	}
+	return nil
+}
+
+// zoneFileRR converts a record to its zone file form. Host names in the
+// data are made fully qualified, since DNSPod may return them without the
+// trailing dot, and TXT values are quoted.
+func zoneFileRR(rec libdns.Record, zone string) (dns.RR, error) {
+	rr := rec.RR()
+	name := dns.Fqdn(libdns.AbsoluteName(rr.Name, zone))
+	data := rr.Data
+
+	switch strings.ToUpper(rr.Type) {
+	case "TXT":
+		data = quoteTXT(data)
+	case "CNAME", "NS", "MX", "SRV":
+		fields := strings.Fields(data)
+		if len(fields) > 0 {
+			fields[len(fields)-1] = dns.Fqdn(fields[len(fields)-1])
+			data = strings.Join(fields, " ")
+		}
+	}
+
+	parsed, err := dns.NewRR(fmt.Sprintf("%s %d IN %s %s", name, int(rr.TTL.Seconds()), rr.Type, data))
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert record %s %s: %w", rr.Name, rr.Type, err)
+	}
+	if parsed == nil {
+		return nil, fmt.Errorf("failed to convert record %s %s: empty record", rr.Name, rr.Type)
+	}
+	return parsed, nil
+}
+
+// quoteTXT quotes a TXT value for a zone file, splitting it into strings
+// of at most 255 bytes
+func quoteTXT(text string) string {
+	var parts []string
+	for {
+		chunk := text
+		if len(chunk) > 255 {
+			chunk = chunk[:255]
+		}
+		chunk = strings.ReplaceAll(chunk, `\`, `\\`)
+		chunk = strings.ReplaceAll(chunk, `"`, `\"`)
+		parts = append(parts, `"`+chunk+`"`)
+
+		if len(text) <= 255 {
+			break
+		}
+		text = text[255:]
+	}
+	return strings.Join(parts, " ")
+}
+
+// Interface guards
+var (
+	_ StandbyTarget = (*ProviderTarget)(nil)
+	_ StandbyTarget = (*ZoneFileTarget)(nil)
+)
@@ -0,0 +1,340 @@
+package dnspod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ZoneState is a portable snapshot of the records of a zone, as written by
+// ExportState and read by ImportState. It can be stored as JSON or YAML.
+type ZoneState struct {
+	Zone    string        `json:"zone"`
+	Records []StateRecord `json:"records"`
+}
+
+// StateRecord is a record in a ZoneState
+type StateRecord struct {
+	// Name is relative to the zone ("@" for the apex)
+	Name string `json:"name"`
+	Type string `json:"type"`
+
+	// TTL is in seconds
+	TTL  int    `json:"ttl"`
+	Data string `json:"data"`
+
+	// Labels are the record's structured remark; Remark is only set for
+	// free-text remarks
+	Labels map[string]string `json:"labels,omitempty"`
+	Remark string            `json:"remark,omitempty"`
+}
+
+// ExportState returns a snapshot of the records of a zone, with their
+// remarks and labels. System records are left out.
+func (p *Provider) ExportState(ctx context.Context, zone string) (*ZoneState, error) {
+	_, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &ZoneState{
+		Zone:    strings.TrimSuffix(zone, "."),
+		Records: make([]StateRecord, 0, len(existingRecords)),
+	}
+	for _, rec := range existingRecords {
+		if isSystemRecord(rec) {
+			continue
+		}
+
+		rr := convertToLibDNSRecord(rec, zone).RR()
+		sr := StateRecord{
+			Name: libdns.RelativeName(rr.Name, zone),
+			Type: rr.Type,
+			TTL:  int(rr.TTL.Seconds()),
+			Data: rr.Data,
+		}
+		if labels, ok := ParseLabels(rec.Remark); ok {
+			sr.Labels = labels
+		} else {
+			sr.Remark = rec.Remark
+		}
+		state.Records = append(state.Records, sr)
+	}
+
+	return state, nil
+}
+
+// ImportState makes the zone of a snapshot match it with Sync. Labels and
+// remarks are written to the records that have them.
+func (p *Provider) ImportState(ctx context.Context, state *ZoneState, opts SyncOptions) (*Plan, []Change, error) {
+	records, err := state.LibDNSRecords()
+	if err != nil {
+		return nil, nil, err
+	}
+	return p.Sync(ctx, state.Zone, records, opts)
+}
+
+// LibDNSRecords converts the snapshot to records with absolute names. The
+// labels and remarks are attached as RecordMetadata.
+func (s *ZoneState) LibDNSRecords() ([]libdns.Record, error) {
+	records := make([]libdns.Record, 0, len(s.Records))
+	for _, sr := range s.Records {
+		rr := libdns.RR{
+			Name: AbsoluteName(sr.Name, s.Zone),
+			Type: strings.ToUpper(sr.Type),
+			TTL:  time.Duration(sr.TTL) * time.Second,
+			Data: sr.Data,
+		}
+
+		rec, err := rr.Parse()
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s record %s: %w", rr.Type, rr.Name, err)
+		}
+		switch rr.Type {
+		case "TLSA":
+			rec, err = parseTLSA(rr.Name, rr.TTL, rr.Data)
+		case "NAPTR":
+			rec, err = parseNAPTR(rr.Name, rr.TTL, rr.Data)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s record %s: %w", rr.Type, rr.Name, err)
+		}
+
+		records = append(records, withMetadata(rec, RecordMetadata{Remark: sr.Remark, Labels: sr.Labels}))
+	}
+	return records, nil
+}
+
+// WriteJSON writes the snapshot as indented JSON
+func (s *ZoneState) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteYAML writes the snapshot as YAML. Strings are double-quoted.
+func (s *ZoneState) WriteYAML(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "zone: %s\n", strconv.Quote(s.Zone))
+	if len(s.Records) == 0 {
+		b.WriteString("records: []\n")
+	} else {
+		b.WriteString("records:\n")
+	}
+
+	for _, sr := range s.Records {
+		fmt.Fprintf(&b, "  - name: %s\n", strconv.Quote(sr.Name))
+		fmt.Fprintf(&b, "    type: %s\n", strconv.Quote(sr.Type))
+		fmt.Fprintf(&b, "    ttl: %d\n", sr.TTL)
+		fmt.Fprintf(&b, "    data: %s\n", strconv.Quote(sr.Data))
+		if len(sr.Labels) > 0 {
+			b.WriteString("    labels:\n")
+			keys := make([]string, 0, len(sr.Labels))
+			for key := range sr.Labels {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(&b, "      %s: %s\n", strconv.Quote(key), strconv.Quote(sr.Labels[key]))
+			}
+		}
+		if sr.Remark != "" {
+			fmt.Fprintf(&b, "    remark: %s\n", strconv.Quote(sr.Remark))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ReadState reads a snapshot written by WriteJSON or WriteYAML. YAML input
+// must keep the layout WriteYAML produces, though scalars may be plain or
+// single-quoted and comments are allowed.
+func ReadState(r io.Reader) (*ZoneState, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zone state: %w", err)
+	}
+
+	var state ZoneState
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		if err := json.Unmarshal(data, &state); err != nil {
+			return nil, fmt.Errorf("failed to parse zone state: %w", err)
+		}
+		return &state, nil
+	}
+
+	if err := state.parseYAML(data); err != nil {
+		return nil, fmt.Errorf("failed to parse zone state: %w", err)
+	}
+	return &state, nil
+}
+
+// parseYAML parses the YAML layout written by WriteYAML
+func (s *ZoneState) parseYAML(data []byte) error {
+	var current *StateRecord
+	labelsIndent := -1
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		content := strings.TrimLeft(line, " ")
+		if content == "" || strings.HasPrefix(content, "#") || content == "---" {
+			continue
+		}
+		indent := len(line) - len(content)
+
+		fail := func(format string, args ...any) error {
+			return fmt.Errorf("line %d: %s", lineNo, fmt.Sprintf(format, args...))
+		}
+
+		if indent == 0 && !strings.HasPrefix(content, "- ") {
+			key, value, err := splitYAMLField(content)
+			if err != nil {
+				return fail("%v", err)
+			}
+			switch key {
+			case "zone":
+				s.Zone = value
+			case "records":
+				if value != "" && value != "[]" {
+					return fail("records must be a list")
+				}
+			default:
+				return fail("unknown field %q", key)
+			}
+			current = nil
+			continue
+		}
+
+		if labelsIndent >= 0 && indent > labelsIndent && !strings.HasPrefix(content, "- ") {
+			key, value, err := splitYAMLField(content)
+			if err != nil {
+				return fail("%v", err)
+			}
+			current.Labels[key] = value
+			continue
+		}
+		labelsIndent = -1
+
+		if rest, ok := strings.CutPrefix(content, "- "); ok {
+			s.Records = append(s.Records, StateRecord{})
+			current = &s.Records[len(s.Records)-1]
+			indent += 2
+			content = strings.TrimLeft(rest, " ")
+		}
+		if current == nil {
+			return fail("field outside of a record")
+		}
+
+		key, value, err := splitYAMLField(content)
+		if err != nil {
+			return fail("%v", err)
+		}
+		switch key {
+		case "name":
+			current.Name = value
+		case "type":
+			current.Type = value
+		case "ttl":
+			ttl, err := strconv.Atoi(value)
+			if err != nil {
+				return fail("invalid TTL %q", value)
+			}
+			current.TTL = ttl
+		case "data":
+			current.Data = value
+		case "remark":
+			current.Remark = value
+		case "labels":
+			if value != "" && value != "{}" {
+				return fail("labels must be a mapping")
+			}
+			current.Labels = make(map[string]string)
+			labelsIndent = indent
+		default:
+			return fail("unknown field %q", key)
+		}
+	}
+
+	return scanner.Err()
+}
+
+// splitYAMLField splits "key: value" and unquotes both
+func splitYAMLField(content string) (string, string, error) {
+	key, rest, err := yamlScalar(content, true)
+	if err != nil {
+		return "", "", err
+	}
+	rest = strings.TrimLeft(rest, " ")
+	if !strings.HasPrefix(rest, ":") {
+		return "", "", fmt.Errorf("expected \"key: value\", got %q", content)
+	}
+
+	value, rest, err := yamlScalar(strings.TrimLeft(rest[1:], " "), false)
+	if err != nil {
+		return "", "", err
+	}
+	if rest = strings.TrimSpace(rest); rest != "" && !strings.HasPrefix(rest, "#") {
+		return "", "", fmt.Errorf("unexpected %q after value", rest)
+	}
+	return key, value, nil
+}
+
+// yamlScalar reads a double-quoted, single-quoted or plain scalar from the
+// start of s and returns it with the rest of s. Plain keys end at ": " and
+// plain values at " #".
+func yamlScalar(s string, isKey bool) (string, string, error) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				i++
+			case '"':
+				value, err := strconv.Unquote(s[:i+1])
+				if err != nil {
+					return "", "", fmt.Errorf("invalid quoted string %s", s[:i+1])
+				}
+				return value, s[i+1:], nil
+			}
+		}
+		return "", "", fmt.Errorf("unterminated string %s", s)
+
+	case strings.HasPrefix(s, "'"):
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), s[i+1:], nil
+		}
+		return "", "", fmt.Errorf("unterminated string %s", s)
+	}
+
+	if isKey {
+		if i := strings.Index(s, ":"); i >= 0 {
+			return strings.TrimSpace(s[:i]), s[i:], nil
+		}
+		return strings.TrimSpace(s), "", nil
+	}
+	if i := strings.Index(s, " #"); i >= 0 {
+		return strings.TrimSpace(s[:i]), s[i:], nil
+	}
+	return strings.TrimSpace(s), "", nil
+}
@@ -0,0 +1,99 @@
+package dnspod
+
+import "time"
+
+// Stats reports the runtime state of a provider's API client
+type Stats struct {
+	// Requests is the number of API responses received
+	Requests int64
+
+	// RateLimited is the number of responses reporting that the account's
+	// request limit was exceeded
+	RateLimited int64
+
+	// Retries is the number of requests sent again after a failure
+	Retries int64
+
+	// Rates are the effective request rates per second by API action ("*"
+	// for the default), lowered by adaptive throttling when needed
+	Rates map[string]float64
+
+	// DomainCache reports on the cached domain list
+	DomainCache CacheStats
+
+	// RecordCache reports on the record cache; it is zero unless
+	// RecordCacheTTL is set
+	RecordCache CacheStats
+
+	// Degraded lists the optional features the API reported as not
+	// supported, with the error that showed it. They are worked around
+	// with the basic per-record actions.
+	Degraded map[string]string
+}
+
+// CacheStats reports how well a cache is doing
+type CacheStats struct {
+	// Hits and Misses count lookups answered from the cache and lookups
+	// that had to call the API
+	Hits   int64
+	Misses int64
+
+	// Evictions counts entries dropped because they expired or their zone
+	// was written to
+	Evictions int64
+
+	// Entries is the number of cached entries and Age the age of the
+	// oldest one
+	Entries int
+	Age     time.Duration
+}
+
+// Stats returns a snapshot of the provider's request and cache statistics
+func (p *Provider) Stats() Stats {
+	client := p.getClient()
+
+	stats := Stats{
+		Requests:    client.requests.Load(),
+		RateLimited: client.rateLimited.Load(),
+		Retries:     client.retries.Load(),
+		DomainCache: client.domainCacheStats(),
+	}
+
+	if len(client.limiters) > 0 {
+		stats.Rates = make(map[string]float64, len(client.limiters))
+		for action, l := range client.limiters {
+			stats.Rates[action] = l.currentRate()
+		}
+	}
+
+	if client.records != nil {
+		stats.RecordCache = client.records.stats()
+	}
+
+	client.capsMutex.Lock()
+	if len(client.degraded) > 0 {
+		stats.Degraded = make(map[string]string, len(client.degraded))
+		for feature, reason := range client.degraded {
+			stats.Degraded[feature] = reason
+		}
+	}
+	client.capsMutex.Unlock()
+
+	return stats
+}
+
+// domainCacheStats returns the statistics of the cached domain list
+func (c *Client) domainCacheStats() CacheStats {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+
+	stats := CacheStats{
+		Hits:   c.domainHits.Load(),
+		Misses: c.domainMisses.Load(),
+	}
+	if len(c.domainList) > 0 {
+		stats.Entries = len(c.domainList)
+		stats.Age = time.Since(c.domainsFetched)
+	}
+	return stats
+}
@@ -0,0 +1,122 @@
+package dnspod
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ConversionError is returned in strict mode when a record cannot be
+// converted without losing or guessing information
+type ConversionError struct {
+	Name   string
+	Type   string
+	Reason string
+
+	// Err is the underlying error, such as a *ParseError, if any
+	Err error
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("lossy conversion of %s record %s: %s", e.Type, e.Name, e.Reason)
+}
+
+func (e *ConversionError) Unwrap() error {
+	return e.Err
+}
+
+// checkOutputConversion reports the lossy or guessed conversions that
+// convertToLibDNSRecord would silently make for a DNSPod record
+func checkOutputConversion(rec record, zone string) error {
+	fail := func(format string, args ...any) error {
+		return &ConversionError{Name: makeAbsoluteName(rec.Name, zone), Type: rec.Type, Reason: fmt.Sprintf(format, args...)}
+	}
+	failParse := func(err error) error {
+		return &ConversionError{Name: makeAbsoluteName(rec.Name, zone), Type: rec.Type, Reason: err.Error(), Err: err}
+	}
+
+	if _, err := ParseTTL(rec.TTL); err != nil {
+		return failParse(err)
+	}
+	if _, _, err := ParseWeight(rec.Weight); err != nil {
+		return failParse(err)
+	}
+
+	switch strings.ToUpper(rec.Type) {
+	case "A", "AAAA":
+		if _, err := netip.ParseAddr(rec.Value); err != nil {
+			return fail("unparseable IP address %q", rec.Value)
+		}
+	case "MX":
+		if _, err := ParseMXPreference(rec.MX); err != nil {
+			return failParse(err)
+		}
+	case "SRV":
+		if _, err := ParseSRVValue(rec.Value); err != nil {
+			return failParse(err)
+		}
+		if len(strings.Fields(rec.Value)) == 3 {
+			if _, err := ParseMXPreference(rec.MX); err != nil {
+				return failParse(err)
+			}
+		}
+	case "CAA":
+		if _, err := ParseCAAValue(rec.Value); err != nil {
+			return failParse(err)
+		}
+	case "HTTPS", "SVCB":
+		if _, err := (libdns.RR{Name: makeAbsoluteName(rec.Name, zone), Type: strings.ToUpper(rec.Type), Data: serviceBindingData(rec)}).Parse(); err != nil {
+			return fail("%v", err)
+		}
+	case "TLSA":
+		if _, err := parseTLSA(rec.Name, 0, rec.Value); err != nil {
+			return fail("%v", err)
+		}
+	case "NAPTR":
+		if _, err := parseNAPTR(rec.Name, 0, rec.Value); err != nil {
+			return fail("%v", err)
+		}
+	}
+
+	return nil
+}
+
+// checkInputConversion reports the lossy or guessed conversions that
+// convertFromLibDNSRecord silently makes for an input record
+func checkInputConversion(libRec libdns.Record) error {
+	rr := libRec.RR()
+	fail := func(format string, args ...any) error {
+		return &ConversionError{Name: rr.Name, Type: rr.Type, Reason: fmt.Sprintf(format, args...)}
+	}
+
+	if rr.TTL%time.Second != 0 {
+		return fail("TTL %s is not a whole number of seconds", rr.TTL)
+	}
+	if rr.TTL <= 0 {
+		return fail("TTL %s is not positive and would be sent as %d", rr.TTL, int(rr.TTL.Seconds()))
+	}
+
+	// The line ID is only sent along with a line name
+	if meta, ok := recordMetadata(libRec); ok && meta.LineID != "" && meta.Line == "" {
+		return fail("line ID %s without a line would be ignored and the record written to the default line", meta.LineID)
+	}
+
+	switch r := libRec.(type) {
+	case libdns.Address:
+		if !r.IP.IsValid() {
+			return fail("invalid IP address")
+		}
+		if r.IP.Is4In6() {
+			return fail("IPv4-mapped address %s would be published as AAAA", r.IP)
+		}
+	case libdns.SRV:
+		if r.Service == "" || r.Transport == "" {
+			return fail("service and transport are required")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package dnspod
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+func TestCheckInputConversion(t *testing.T) {
+	tests := []struct {
+		name string
+		in   libdns.Record
+		err  string
+	}{
+		{"valid", libdns.TXT{Name: "a", TTL: time.Minute, Text: "x"}, ""},
+		{"zero TTL", libdns.TXT{Name: "a", Text: "x"}, "would be sent as 0"},
+		{"fractional TTL", libdns.TXT{Name: "a", TTL: 1500 * time.Millisecond, Text: "x"}, "whole number of seconds"},
+		{"line", libdns.TXT{Name: "a", TTL: time.Minute, Text: "x", ProviderData: RecordMetadata{Line: "电信", LineID: "10=0"}}, ""},
+		{"line ID only", libdns.TXT{Name: "a", TTL: time.Minute, Text: "x", ProviderData: &RecordMetadata{LineID: "10=0"}}, "line ID 10=0 without a line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkInputConversion(tt.in)
+			if tt.err == "" && err != nil || tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("got %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckOutputConversionLines(t *testing.T) {
+	tests := []struct {
+		name string
+		rec  record
+		err  bool
+	}{
+		{"typed record on a line", record{Name: "www", Type: "A", Value: "192.0.2.1", TTL: "600", Line: "电信"}, false},
+		{"other type on the default line", record{Name: "www", Type: "URL", Value: "https://example.net", TTL: "600", Line: "默认"}, false},
+		{"other type on the intl default line", record{Name: "www", Type: "URL", Value: "https://example.net", TTL: "600", Line: "Default"}, false},
+		{"other type on a line", record{Name: "www", Type: "URL", Value: "https://example.net", TTL: "600", Line: "电信"}, false},
+		{"unparseable address on a line", record{Name: "www", Type: "A", Value: "bogus", TTL: "600", Line: "电信"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkOutputConversion(tt.rec, "example.com."); (err != nil) != tt.err {
+				t.Errorf("got %v, want error %v", err, tt.err)
+			}
+			if meta, ok := recordMetadata(convertToLibDNSRecord(tt.rec, "example.com.")); !ok || meta.Line != tt.rec.Line {
+				t.Errorf("converted with metadata %+v, want line %s", meta, tt.rec.Line)
+			}
+		})
+	}
+}
@@ -0,0 +1,165 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libdns/libdns"
+)
+
+// SyncOptions configures PlanSync and Sync
+type SyncOptions struct {
+	// Prune deletes records whose RRset (name, type and line) does not
+	// appear in the desired records at all. Without it, only RRsets present
+	// in the desired records are reconciled.
+	Prune bool
+
+	// DryRun makes Sync return the plan without applying it
+	DryRun bool
+
+	// Select restricts the existing records Sync may update or delete, for
+	// example to those labeled "managed-by=ci", so that hand-managed
+	// records are never touched. It selects every record by default.
+	Select RecordFilter
+}
+
+// rrsetKey identifies an RRset by relative name, type and line. The line
+// is empty where lines do not apply.
+type rrsetKey struct {
+	name string
+	typ  string
+	line string
+}
+
+// PlanSync plans the changes that make the zone match the desired records.
+// For every RRset in desired, by name, type and line (the default line
+// unless given in RecordMetadata), records with identical data are kept (with
+// their TTL updated if needed), missing ones are created and the rest are
+// deleted. Creates come first and deletes last. System records are never
+// touched.
+//
+// Records carrying RecordMetadata labels or a remark are updated when their
+// remark differs; records without one keep theirs.
+func (p *Provider) PlanSync(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (*Plan, error) {
+	client, _, existingRecords, err := p.listZoneRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existingBySet := make(map[rrsetKey][]record)
+	for _, rec := range existingRecords {
+		if isSystemRecord(rec) {
+			continue
+		}
+		key := client.storedRRset(rec)
+		existingBySet[key] = append(existingBySet[key], rec)
+	}
+
+	desiredBySet := make(map[rrsetKey][]libdns.Record)
+	var order []rrsetKey
+	for _, libRec := range desired {
+		key := client.inputRRset(convertFromLibDNSRecord(libRec, zone))
+		if _, ok := desiredBySet[key]; !ok {
+			order = append(order, key)
+		}
+		desiredBySet[key] = append(desiredBySet[key], libRec)
+	}
+
+	plan := &Plan{Zone: zone}
+	var updates, deletes []Change
+
+	for _, key := range order {
+		remaining := existingBySet[key]
+
+		for _, libRec := range desiredBySet[key] {
+			want := convertFromLibDNSRecord(libRec, zone)
+
+			matched := -1
+			for i, have := range remaining {
+				if sameRecordValue(key.typ, have.Value, want.Value) && (!usesMX(key.typ) || have.MX == want.MX) {
+					matched = i
+					break
+				}
+			}
+
+			if matched < 0 {
+				plan.Changes = append(plan.Changes, Change{Op: ChangeCreate, After: libRec})
+				continue
+			}
+
+			have := remaining[matched]
+			remaining = append(remaining[:matched:matched], remaining[matched+1:]...)
+
+			if !opts.Select.matches(have, zone) {
+				continue
+			}
+			if have.TTL != want.TTL || (want.Remark != "" && want.Remark != have.Remark) {
+				existing := have
+				updates = append(updates, Change{
+					Op:       ChangeUpdate,
+					Before:   convertToLibDNSRecord(have, zone),
+					After:    libRec,
+					existing: &existing,
+				})
+			}
+		}
+
+		for _, have := range remaining {
+			if !opts.Select.matches(have, zone) {
+				continue
+			}
+			existing := have
+			deletes = append(deletes, Change{
+				Op:       ChangeDelete,
+				Before:   convertToLibDNSRecord(have, zone),
+				existing: &existing,
+			})
+		}
+	}
+
+	if opts.Prune {
+		for _, rec := range existingRecords {
+			if isSystemRecord(rec) || !opts.Select.matches(rec, zone) {
+				continue
+			}
+			if _, ok := desiredBySet[client.storedRRset(rec)]; ok {
+				continue
+			}
+			existing := rec
+			deletes = append(deletes, Change{
+				Op:       ChangeDelete,
+				Before:   convertToLibDNSRecord(rec, zone),
+				existing: &existing,
+			})
+		}
+	}
+
+	plan.Changes = append(plan.Changes, updates...)
+	plan.Changes = append(plan.Changes, deletes...)
+
+	return plan, nil
+}
+
+// Sync makes the zone match the desired records, as planned by PlanSync.
+// The plan goes through the configured Approver before it is applied; if
+// the zone changes while it waits for approval, ErrPlanChanged is returned
+// and nothing is applied. It returns the plan and the applied changes;
+// with DryRun, nothing is applied.
+func (p *Provider) Sync(ctx context.Context, zone string, desired []libdns.Record, opts SyncOptions) (*Plan, []Change, error) {
+	if opts.DryRun {
+		plan, err := p.PlanSync(ctx, zone, desired, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return plan, nil, nil
+	}
+
+	plan, applied, err := p.approveAndApply(ctx, zone, func(ctx context.Context) (*Plan, error) {
+		return p.PlanSync(ctx, zone, desired, opts)
+	})
+	if err != nil {
+		return plan, applied, fmt.Errorf("failed to sync zone %s: %w", zone, err)
+	}
+
+	return plan, applied, nil
+}
@@ -0,0 +1,107 @@
+package dnspod_test
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+
+	dnspod "github.com/r6c/dnspodGlobal"
+)
+
+func TestPlanSyncMatchesLines(t *testing.T) {
+	backend := newFakeDNSPod("example.com")
+	server := httptest.NewServer(backend)
+	t.Cleanup(server.Close)
+
+	provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL}
+	ctx := context.Background()
+	const zone = "example.com."
+
+	_, err := provider.AppendRecords(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a"},
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a", ProviderData: dnspod.RecordMetadata{Line: "电信"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := provider.PlanSync(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "a"},
+	}, dnspod.SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !plan.Empty() {
+		t.Errorf("plan has %d changes, want none: %+v", len(plan.Changes), plan.Changes)
+	}
+
+	plan, err = provider.PlanSync(ctx, zone, []libdns.Record{
+		libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "b", ProviderData: dnspod.RecordMetadata{Line: "电信"}},
+	}, dnspod.SyncOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Changes) != 2 || plan.Changes[0].Op != dnspod.ChangeCreate || plan.Changes[1].Op != dnspod.ChangeDelete {
+		t.Fatalf("plan %+v, want a create and a delete", plan.Changes)
+	}
+	if meta, _ := plan.Changes[1].Before.(libdns.TXT).ProviderData.(dnspod.RecordMetadata); meta.Line != "电信" {
+		t.Errorf("plan deletes the record on line %q, want 电信", meta.Line)
+	}
+}
+
+func TestSyncApprovesOutsideTheZoneLock(t *testing.T) {
+	const zone = "example.com."
+	desired := []libdns.Record{libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "new"}}
+
+	tests := []struct {
+		name       string
+		concurrent bool
+		err        error
+		want       string
+	}{
+		{"unchanged zone", false, nil, "默认:new"},
+		{"zone changed during approval", true, dnspod.ErrPlanChanged, "默认:other"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := newFakeDNSPod("example.com")
+			backend.add("test", "TXT", "old", "600")
+			server := httptest.NewServer(backend)
+			t.Cleanup(server.Close)
+
+			provider := &dnspod.Provider{LoginToken: "1,token", Endpoint: server.URL, SerializeZoneWrites: true}
+			provider.Approver = dnspod.ApproverFunc(func(ctx context.Context, plan *dnspod.Plan) (bool, error) {
+				if !tt.concurrent {
+					return true, nil
+				}
+
+				// A write to the zone must not wait for the approval
+				done := make(chan error, 1)
+				go func() {
+					_, err := provider.SetRecords(context.Background(), zone, []libdns.Record{
+						libdns.TXT{Name: "test", TTL: 10 * time.Minute, Text: "other"},
+					})
+					done <- err
+				}()
+				select {
+				case err := <-done:
+					return err == nil, err
+				case <-time.After(5 * time.Second):
+					return false, errors.New("a write to the zone blocked during approval")
+				}
+			})
+
+			_, _, err := provider.Sync(context.Background(), zone, desired, dnspod.SyncOptions{})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got error %v, want %v", err, tt.err)
+			}
+			if got := txtRecords(backend); got != tt.want {
+				t.Errorf("zone has %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
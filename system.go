@@ -0,0 +1,268 @@
+package dnspod
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// RecordMetadata is attached as ProviderData to the records returned by
+// this provider
+type RecordMetadata struct {
+	// ID is the DNSPod record ID
+	ID string
+
+	// System is set for records DNSPod manages itself, such as the apex NS
+	// records and the synthesized SOA. They cannot be modified or deleted.
+	System bool
+
+	// UpdatedOn is when DNSPod last changed the record. It is zero for
+	// records returned by writes, whose responses do not include it.
+	UpdatedOn time.Time
+
+	// Enabled reports whether the record is being served. Paused and
+	// spam-flagged records are not.
+	Enabled bool
+
+	// Status is the record state as reported by DNSPod
+	Status RecordStatus
+
+	// Remark is the record's remark. Labels holds the remark parsed as
+	// "key=value;key2=value2", and is nil if the remark is free text.
+	//
+	// Records passed to the provider may carry RecordMetadata too: their
+	// Labels, or Remark if there are none, are written as the remark.
+	Remark string
+	Labels map[string]string
+
+	// Line is the line (线路) the record is served on, such as "默认",
+	// "电信" or "境外", and LineID its ID. Weight is the record's weight
+	// among the records with the same name, type and line, or nil if it
+	// has none.
+	//
+	// On records passed to the provider, Line selects the line to write
+	// to, and SetRecords and DeleteRecords only match existing records on
+	// that line. Records without a line are on the default line. LineID
+	// is sent along with Line if set, and Weight (0 to 100) is written if
+	// not nil.
+	Line   string
+	LineID string
+	Weight *int
+}
+
+// recordMetadata returns the RecordMetadata attached to a record, if any
+func recordMetadata(libRec libdns.Record) (RecordMetadata, bool) {
+	var data any
+	switch r := libRec.(type) {
+	case libdns.Address:
+		data = r.ProviderData
+	case libdns.TXT:
+		data = r.ProviderData
+	case libdns.CNAME:
+		data = r.ProviderData
+	case libdns.MX:
+		data = r.ProviderData
+	case libdns.NS:
+		data = r.ProviderData
+	case libdns.SRV:
+		data = r.ProviderData
+	case libdns.CAA:
+		data = r.ProviderData
+	case libdns.ServiceBinding:
+		data = r.ProviderData
+	case TLSA:
+		data = r.ProviderData
+	case NAPTR:
+		data = r.ProviderData
+	case SOA:
+		data = r.ProviderData
+	case GenericRecord:
+		data = r.ProviderData
+	}
+
+	switch meta := data.(type) {
+	case RecordMetadata:
+		return meta, true
+	case *RecordMetadata:
+		if meta != nil {
+			return *meta, true
+		}
+	}
+	return RecordMetadata{}, false
+}
+
+// RecordStatus is the state of a record
+type RecordStatus string
+
+const (
+	RecordStatusEnabled  RecordStatus = "enabled"
+	RecordStatusDisabled RecordStatus = "disabled"
+
+	// RecordStatusSpam marks records DNSPod flagged as abusive; they are
+	// not served and cannot be enabled through the API
+	RecordStatusSpam RecordStatus = "spam"
+)
+
+// withMetadata attaches RecordMetadata to a record. Records of types
+// without ProviderData are returned unchanged.
+func withMetadata(libRec libdns.Record, meta RecordMetadata) libdns.Record {
+	switch r := libRec.(type) {
+	case libdns.Address:
+		r.ProviderData = meta
+		return r
+	case libdns.TXT:
+		r.ProviderData = meta
+		return r
+	case libdns.CNAME:
+		r.ProviderData = meta
+		return r
+	case libdns.MX:
+		r.ProviderData = meta
+		return r
+	case libdns.NS:
+		r.ProviderData = meta
+		return r
+	case libdns.SRV:
+		r.ProviderData = meta
+		return r
+	case libdns.CAA:
+		r.ProviderData = meta
+		return r
+	case libdns.ServiceBinding:
+		r.ProviderData = meta
+		return r
+	case TLSA:
+		r.ProviderData = meta
+		return r
+	case NAPTR:
+		r.ProviderData = meta
+		return r
+	case GenericRecord:
+		r.ProviderData = meta
+		return r
+	}
+	return libRec
+}
+
+// parseRecordStatus normalizes the status and enabled fields of a record.
+// Unknown statuses are passed through in lower case. Records without either
+// field, such as those returned by writes, count as enabled.
+func parseRecordStatus(status, enabled string) (RecordStatus, bool) {
+	var s RecordStatus
+	switch normalized := strings.ToLower(status); {
+	case normalized == "enable" || normalized == "enabled":
+		s = RecordStatusEnabled
+	case normalized == "disable" || normalized == "disabled":
+		s = RecordStatusDisabled
+	case strings.Contains(normalized, "spam"):
+		s = RecordStatusSpam
+	case normalized == "" && enabled == "1":
+		s = RecordStatusEnabled
+	case normalized == "" && enabled == "0":
+		s = RecordStatusDisabled
+	default:
+		s = RecordStatus(normalized)
+	}
+
+	if s == RecordStatusDisabled || s == RecordStatusSpam {
+		return s, false
+	}
+	if e, err := ParseEnabled(enabled); err == nil {
+		return s, e
+	}
+	return s, true
+}
+
+// dnspodNameServerSuffixes are the domains DNSPod's assigned nameservers live under
+var dnspodNameServerSuffixes = []string{
+	".dnspod.net", ".dnspod.com",
+	".dnsv2.com", ".dnsv3.com", ".dnsv4.com", ".dnsv5.com",
+}
+
+// SOA is a read-only start of authority record synthesized from the
+// information DNSPod exposes. DNSPod does not return SOA records through
+// its API, so the timer values are DNSPod's published defaults.
+type SOA struct {
+	Name    string
+	TTL     time.Duration
+	MName   string
+	RName   string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+
+	// Optional custom data associated with the provider serving this record.
+	ProviderData any
+}
+
+// RR implements libdns.Record
+func (s SOA) RR() libdns.RR {
+	return libdns.RR{
+		Name: s.Name,
+		TTL:  s.TTL,
+		Type: "SOA",
+		Data: fmt.Sprintf("%s %s %d %d %d %d %d", s.MName, s.RName, s.Serial, s.Refresh, s.Retry, s.Expire, s.Minimum),
+	}
+}
+
+// isSystemRecord reports whether a record is managed by DNSPod itself
+func isSystemRecord(rec record) bool {
+	if rec.Name != "@" || !strings.EqualFold(rec.Type, "NS") {
+		return false
+	}
+	host := strings.ToLower(strings.TrimSuffix(rec.Value, "."))
+	for _, suffix := range dnspodNameServerSuffixes {
+		if strings.HasSuffix(host, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// synthesizeSOA builds a SOA record for a zone from its system NS records.
+// The serial is derived from the most recent record update.
+func synthesizeSOA(records []record, zone string) (SOA, bool) {
+	var mname string
+	var serial int64
+	for _, rec := range records {
+		if mname == "" && isSystemRecord(rec) {
+			mname = strings.TrimSuffix(rec.Value, ".") + "."
+		}
+		if t := parseTimestamp(rec.UpdatedOn); t.Unix() > serial {
+			serial = t.Unix()
+		}
+	}
+	if mname == "" {
+		return SOA{}, false
+	}
+
+	return SOA{
+		Name:         NormalizeName(makeAbsoluteName("@", zone)),
+		TTL:          600 * time.Second,
+		MName:        mname,
+		RName:        "freednsadmin.dnspod.com.",
+		Serial:       uint32(serial),
+		Refresh:      3600,
+		Retry:        180,
+		Expire:       1209600,
+		Minimum:      180,
+		ProviderData: RecordMetadata{System: true, Enabled: true, Status: RecordStatusEnabled},
+	}, true
+}
+
+// dnspodLocation is the time zone DNSPod reports timestamps in
+var dnspodLocation = time.FixedZone("CST", 8*60*60)
+
+// parseTimestamp parses a DNSPod timestamp, returning the zero time if it
+// is empty or malformed
+func parseTimestamp(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", s, dnspodLocation)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
@@ -0,0 +1,115 @@
+package dnspod
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// TLSA represents a TLSA record used for DANE (RFC 6698). The name must
+// include the port and transport labels, e.g. "_443._tcp.www.example.com.".
+type TLSA struct {
+	Name         string
+	TTL          time.Duration
+	Usage        uint8  // 0 PKIX-TA, 1 PKIX-EE, 2 DANE-TA, 3 DANE-EE
+	Selector     uint8  // 0 full certificate, 1 SubjectPublicKeyInfo
+	MatchingType uint8  // 0 exact match, 1 SHA-256, 2 SHA-512
+	CertData     string // hex-encoded certificate association data
+
+	// Optional custom data associated with the provider serving this record.
+	ProviderData any
+}
+
+// RR implements libdns.Record
+func (t TLSA) RR() libdns.RR {
+	return libdns.RR{
+		Name: t.Name,
+		TTL:  t.TTL,
+		Type: "TLSA",
+		Data: fmt.Sprintf("%d %d %d %s", t.Usage, t.Selector, t.MatchingType, strings.ToLower(t.CertData)),
+	}
+}
+
+// parseTLSA parses a "usage selector matching-type data" value. Whitespace
+// inside the certificate data is allowed, as in zone file syntax.
+func parseTLSA(name string, ttl time.Duration, value string) (TLSA, error) {
+	fields := strings.Fields(value)
+	if len(fields) < 4 {
+		return TLSA{}, fmt.Errorf("invalid TLSA record value %q: expected \"usage selector matching-type data\"", value)
+	}
+
+	var params [3]uint8
+	for i, field := range []string{"usage", "selector", "matching type"} {
+		n, err := strconv.ParseUint(fields[i], 10, 8)
+		if err != nil {
+			return TLSA{}, fmt.Errorf("invalid TLSA record value %q: bad %s", value, field)
+		}
+		params[i] = uint8(n)
+	}
+
+	tlsa := TLSA{
+		Name:         name,
+		TTL:          ttl,
+		Usage:        params[0],
+		Selector:     params[1],
+		MatchingType: params[2],
+		CertData:     strings.ToLower(strings.Join(fields[3:], "")),
+	}
+
+	return tlsa, validateTLSAFields(tlsa)
+}
+
+// validateTLSAFields checks the parameter ranges and certificate data
+func validateTLSAFields(t TLSA) error {
+	if t.Usage > 3 {
+		return fmt.Errorf("invalid TLSA usage %d: must be 0-3", t.Usage)
+	}
+	if t.Selector > 1 {
+		return fmt.Errorf("invalid TLSA selector %d: must be 0 or 1", t.Selector)
+	}
+	if t.MatchingType > 2 {
+		return fmt.Errorf("invalid TLSA matching type %d: must be 0-2", t.MatchingType)
+	}
+
+	data, err := hex.DecodeString(t.CertData)
+	if err != nil || len(data) == 0 {
+		return fmt.Errorf("invalid TLSA certificate data: must be non-empty hex")
+	}
+
+	switch t.MatchingType {
+	case 1:
+		if len(data) != 32 {
+			return fmt.Errorf("invalid TLSA certificate data: SHA-256 digest must be 32 bytes, got %d", len(data))
+		}
+	case 2:
+		if len(data) != 64 {
+			return fmt.Errorf("invalid TLSA certificate data: SHA-512 digest must be 64 bytes, got %d", len(data))
+		}
+	}
+
+	return nil
+}
+
+// validateTLSA checks that a DNSPod TLSA record has a _port._proto name and
+// a well-formed value
+func validateTLSA(rec record) error {
+	labels := strings.Split(rec.Name, ".")
+	if len(labels) < 2 || !strings.HasPrefix(labels[0], "_") || !strings.HasPrefix(labels[1], "_") {
+		return fmt.Errorf("invalid TLSA record name %q: must start with _port._proto", rec.Name)
+	}
+	if _, err := strconv.ParseUint(strings.TrimPrefix(labels[0], "_"), 10, 16); err != nil {
+		return fmt.Errorf("invalid TLSA record name %q: bad port label", rec.Name)
+	}
+	switch strings.ToLower(labels[1]) {
+	case "_tcp", "_udp", "_sctp":
+	default:
+		return fmt.Errorf("invalid TLSA record name %q: transport must be _tcp, _udp or _sctp", rec.Name)
+	}
+
+	_, err := parseTLSA(rec.Name, 0, rec.Value)
+	return err
+}
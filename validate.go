@@ -0,0 +1,56 @@
+package dnspod
+
+import (
+	"fmt"
+	"strings"
+)
+
+// supportedRecordTypes lists the record types each API endpoint accepts.
+// Plan-specific restrictions are still reported by the API itself.
+var supportedRecordTypes = map[string]map[string]bool{
+	baseURL:   cnRecordTypes,
+	v3BaseURL: cnRecordTypes,
+	intlBaseURL: {
+		"A": true, "AAAA": true, "CNAME": true, "MX": true, "TXT": true,
+		"NS": true, "SRV": true, "CAA": true, "SPF": true, "URL": true,
+	},
+}
+
+// cnRecordTypes are the types accepted by dnsapi.cn and API 3.0, which
+// share a backend
+var cnRecordTypes = map[string]bool{
+	"A": true, "AAAA": true, "CNAME": true, "MX": true, "TXT": true,
+	"NS": true, "SRV": true, "CAA": true, "SPF": true, "HTTPS": true,
+	"SVCB": true, "TLSA": true, "NAPTR": true,
+	"显性URL": true, "隐性URL": true,
+}
+
+// UnsupportedTypeError is returned when a record type is not accepted by
+// DNSPod, before any request is sent
+type UnsupportedTypeError struct {
+	Type     string
+	Endpoint string
+}
+
+func (e *UnsupportedTypeError) Error() string {
+	return fmt.Sprintf("type %s is not supported by DNSPod for this domain", e.Type)
+}
+
+// validateRecord checks a converted record against DNSPod's requirements
+// before it is sent to the API
+func (c *Client) validateRecord(rec record) error {
+	// Unknown endpoints (proxies, mirrors) are not restricted
+	if supported, ok := supportedRecordTypes[c.baseURL]; ok && !supported[strings.ToUpper(rec.Type)] {
+		return &UnsupportedTypeError{Type: rec.Type, Endpoint: c.baseURL}
+	}
+
+	switch strings.ToUpper(rec.Type) {
+	case "SRV":
+		return validateSRV(rec)
+	case "TLSA":
+		return validateTLSA(rec)
+	case "NAPTR":
+		return validateNAPTR(rec)
+	}
+	return nil
+}
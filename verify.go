@@ -0,0 +1,71 @@
+package dnspod
+
+import (
+	"fmt"
+	"strings"
+)
+
+// VerificationError reports that a written record was stored differently
+// than requested. The record has still been written as DNSPod stored it.
+type VerificationError struct {
+	Name  string
+	Type  string
+	Field string
+
+	Requested string
+	Stored    string
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("DNSPod stored %s %q instead of the requested %q for %s record %s", e.Field, e.Stored, e.Requested, e.Type, e.Name)
+}
+
+// verifyRecord compares a record read back after a write with the record
+// that was sent. The TTL is only compared if checkTTL is set and a TTL was
+// requested.
+func (c *Client) verifyRecord(zone string, sent, stored record, checkTTL bool) error {
+	fail := func(field, requested, actual string) error {
+		return &VerificationError{
+			Name:      makeAbsoluteName(sent.Name, zone),
+			Type:      sent.Type,
+			Field:     field,
+			Requested: requested,
+			Stored:    actual,
+		}
+	}
+
+	if !sameRecordValue(sent.Type, stored.Value, sent.Value) {
+		return fail("value", sent.Value, stored.Value)
+	}
+	if usesMX(sent.Type) && sent.MX != "" && stored.MX != sent.MX {
+		field := "MX preference"
+		if strings.EqualFold(sent.Type, "SRV") {
+			field = "SRV priority"
+		}
+		return fail(field, sent.MX, stored.MX)
+	}
+	if checkTTL && sent.TTL != "" && stored.TTL != sent.TTL {
+		return fail("TTL", sent.TTL, stored.TTL)
+	}
+
+	if !c.sameLine(sent, stored) {
+		params := make(map[string]string)
+		c.lineParams(params, sent)
+		return fail("line", params["record_line"], stored.Line)
+	}
+
+	return nil
+}
+
+// sameLine reports whether a stored record is on the line a record would
+// be written to. Lines are compared by ID when one is sent and stored, and
+// by name regardless of case otherwise.
+func (c *Client) sameLine(sent, stored record) bool {
+	params := make(map[string]string)
+	c.lineParams(params, sent)
+
+	if id := params["record_line_id"]; id != "" && stored.LineID != "" {
+		return stored.LineID == id
+	}
+	return strings.EqualFold(LocalizeLine(stored.Line, c.baseURL), params["record_line"])
+}
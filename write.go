@@ -0,0 +1,192 @@
+package dnspod
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TTLPolicy controls what happens when DNSPod stores a different TTL than
+// the one requested, which it does silently when a TTL is below the
+// minimum allowed by the domain's plan
+type TTLPolicy string
+
+const (
+	// TTLPolicyIgnore accepts coerced TTLs without checking (the default)
+	TTLPolicyIgnore TTLPolicy = ""
+
+	// TTLPolicyWarn reports coerced TTLs through the warning handler
+	TTLPolicyWarn TTLPolicy = "warn"
+
+	// TTLPolicyError returns a TTLCoercionError. The record has still been
+	// written with the TTL DNSPod chose.
+	TTLPolicyError TTLPolicy = "error"
+)
+
+// TTLCoercionError reports that DNSPod stored a record with a different
+// TTL than requested
+type TTLCoercionError struct {
+	Name      string
+	Type      string
+	Requested int
+	Applied   int
+}
+
+func (e *TTLCoercionError) Error() string {
+	return fmt.Sprintf("DNSPod applied TTL %d instead of the requested %d to %s record %s", e.Applied, e.Requested, e.Type, e.Name)
+}
+
+// createRecord creates a record and runs the post-write checks. If a check
+// fails after the record was written, the stored record is returned along
+// with the error.
+func (p *Provider) createRecord(ctx context.Context, client *Client, zone, domainID string, rec record) (*record, error) {
+	if p.IdempotencyWindow > 0 {
+		existing, err := p.recentDuplicate(ctx, client, domainID, rec)
+		if err != nil {
+			return nil, err
+		}
+		if existing != nil {
+			return p.afterWrite(ctx, client, zone, domainID, rec, existing)
+		}
+	}
+
+	created, err := p.writeWeighted(client, &rec, func(rec record) (*record, error) {
+		return client.createRecord(ctx, domainID, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p.afterWrite(ctx, client, zone, domainID, rec, created)
+}
+
+// recentDuplicate returns a record identical to rec that was changed
+// within the idempotency window, presumably by an earlier attempt at the
+// same create whose response was lost, or nil if there is none
+func (p *Provider) recentDuplicate(ctx context.Context, client *Client, domainID string, rec record) (*record, error) {
+	existingRecords, err := client.findRecords(ctx, domainID, rec.Name, rec.Type)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for an earlier create: %w", err)
+	}
+
+	for _, existing := range existingRecords {
+		if !strings.EqualFold(existing.Name, rec.Name) ||
+			!strings.EqualFold(existing.Type, rec.Type) ||
+			!sameRecordValue(rec.Type, existing.Value, rec.Value) ||
+			(usesMX(rec.Type) && existing.MX != rec.MX) ||
+			!client.sameLine(rec, existing) {
+			continue
+		}
+
+		updated := parseTimestamp(existing.UpdatedOn)
+		if updated.IsZero() || time.Since(updated) > p.IdempotencyWindow {
+			continue
+		}
+
+		adopted := existing
+		return &adopted, nil
+	}
+	return nil, nil
+}
+
+// updateRecord updates an existing record and runs the post-write checks,
+// like createRecord. The remark is only written if it changed.
+func (p *Provider) updateRecord(ctx context.Context, client *Client, zone, domainID string, existing, rec record) (*record, error) {
+	if rec.Remark == existing.Remark {
+		rec.Remark = ""
+	}
+
+	updated, err := p.writeWeighted(client, &rec, func(rec record) (*record, error) {
+		return client.updateRecord(ctx, domainID, existing.ID, rec)
+	})
+	if err != nil {
+		return nil, err
+	}
+	updated.Remark = existing.Remark
+	return p.afterWrite(ctx, client, zone, domainID, rec, updated)
+}
+
+// writeWeighted sends a create or modify of rec. The weight is dropped if
+// weights are unavailable, and if the API rejects it as not supported, the
+// write is retried without it.
+func (p *Provider) writeWeighted(client *Client, rec *record, write func(record) (*record, error)) (*record, error) {
+	if rec.Weight != "" && !client.capabilities().Weights {
+		rec.Weight = ""
+	}
+
+	stored, err := write(*rec)
+	if rec.Weight != "" && errors.Is(err, ErrNotSupported) {
+		p.degrade(client, featureWeight, err)
+		rec.Weight = ""
+		stored, err = write(*rec)
+	}
+	return stored, err
+}
+
+// afterWrite sets the remark of a written record, which create and modify
+// requests cannot carry, and checks the record against what was requested
+func (p *Provider) afterWrite(ctx context.Context, client *Client, zone, domainID string, sent record, stored *record) (*record, error) {
+	if sent.Remark != "" && sent.Remark != stored.Remark && client.capabilities().Remarks {
+		err := client.setRemark(ctx, domainID, stored.ID, sent.Remark)
+		switch {
+		case errors.Is(err, ErrNotSupported):
+			p.degrade(client, featureRemark, err)
+		case err != nil:
+			return stored, err
+		default:
+			stored.Remark = sent.Remark
+		}
+	}
+
+	checkTTL := p.TTLCoercion != TTLPolicyIgnore && sent.TTL != ""
+	if !checkTTL && !p.Verify {
+		return stored, nil
+	}
+
+	// Create and modify responses do not include the TTL, so read it back
+	current, err := client.getRecord(ctx, domainID, stored.ID)
+	if err != nil {
+		return stored, fmt.Errorf("failed to read back written record: %w", err)
+	}
+	stored.TTL = current.TTL
+	stored.Line = current.Line
+	stored.LineID = current.LineID
+
+	if p.Verify {
+		if err := client.verifyRecord(zone, sent, *current, !checkTTL); err != nil {
+			return stored, err
+		}
+	}
+
+	if !checkTTL || current.TTL == sent.TTL {
+		return stored, nil
+	}
+
+	requested, _ := strconv.Atoi(sent.TTL)
+	applied, _ := strconv.Atoi(current.TTL)
+	coercion := &TTLCoercionError{
+		Name:      makeAbsoluteName(sent.Name, zone),
+		Type:      sent.Type,
+		Requested: requested,
+		Applied:   applied,
+	}
+
+	if p.TTLCoercion == TTLPolicyError {
+		return stored, coercion
+	}
+	p.warn(coercion)
+	return stored, nil
+}
+
+// warn reports a non-fatal problem to the warning handler, or the standard
+// logger if none is configured
+func (p *Provider) warn(err error) {
+	if p.OnWarning != nil {
+		p.OnWarning(err)
+		return
+	}
+	log.Printf("dnspod: %v", err)
+}
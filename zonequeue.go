@@ -0,0 +1,169 @@
+package dnspod
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/libdns/libdns"
+)
+
+// zoneQueue serializes writes per zone. Each zone has a one-slot channel;
+// writers queue on it in arrival order.
+type zoneQueue struct {
+	mutex sync.Mutex
+	slots map[string]chan struct{}
+}
+
+// heldZoneKey marks a zone as held in a context, so that nested calls made
+// while holding it do not queue behind themselves. In single-writer mode
+// the empty zone stands for all zones.
+type heldZoneKey struct{ zone string }
+
+// slot returns the queue slot of a zone, creating it if needed
+func (q *zoneQueue) slot(zone string) chan struct{} {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+
+	if q.slots == nil {
+		q.slots = make(map[string]chan struct{})
+	}
+	slot, ok := q.slots[zone]
+	if !ok {
+		slot = make(chan struct{}, 1)
+		q.slots[zone] = slot
+	}
+	return slot
+}
+
+// lockZone waits for the zone's turn when SerializeZoneWrites is set, or
+// for the provider's turn when SingleWriter is set, so that the reads and
+// writes of one mutation are not interleaved with those of another. It
+// returns a context to use while holding the zone and a function that
+// releases it.
+func (p *Provider) lockZone(ctx context.Context, zone string) (context.Context, func(), error) {
+	if !p.SerializeZoneWrites && !p.SingleWriter {
+		return ctx, func() {}, nil
+	}
+
+	key := heldZoneKey{strings.ToLower(strings.TrimSuffix(zone, "."))}
+	if p.SingleWriter {
+		key.zone = ""
+	}
+	if ctx.Value(key) != nil {
+		return ctx, func() {}, nil
+	}
+
+	slot := p.getClient().writes.slot(key.zone)
+	select {
+	case slot <- struct{}{}:
+	case <-ctx.Done():
+		return ctx, nil, ctx.Err()
+	}
+
+	return context.WithValue(ctx, key, true), func() { <-slot }, nil
+}
+
+// rrsetLocks serializes writes per RRset. Locks are created on demand and
+// dropped when no caller holds or waits for them.
+type rrsetLocks struct {
+	mutex sync.Mutex
+	locks map[rrsetLockKey]*rrsetLock
+}
+
+// rrsetLockKey identifies an RRset across zones
+type rrsetLockKey struct {
+	zone string
+	name string
+	typ  string
+}
+
+type rrsetLock struct {
+	slot chan struct{}
+	refs int
+}
+
+// acquire takes a reference to the lock of an RRset
+func (l *rrsetLocks) acquire(key rrsetLockKey) *rrsetLock {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.locks == nil {
+		l.locks = make(map[rrsetLockKey]*rrsetLock)
+	}
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &rrsetLock{slot: make(chan struct{}, 1)}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// release drops a reference to the lock of an RRset
+func (l *rrsetLocks) release(key rrsetLockKey) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	lock := l.locks[key]
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+// lockRRsets waits until no other SetRecords call holds any of the RRsets
+// of records, so that calls for different names run in parallel while
+// calls for the same RRset cannot interleave their list-then-modify steps.
+// Locks are taken in a fixed order to avoid deadlocks. It returns a
+// function that releases them.
+func (p *Provider) lockRRsets(ctx context.Context, zone string, records []libdns.Record) (func(), error) {
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	seen := make(map[rrsetLockKey]bool, len(records))
+	keys := make([]rrsetLockKey, 0, len(records))
+	for _, rec := range records {
+		rr := rec.RR()
+		key := rrsetLockKey{
+			zone: zone,
+			name: strings.ToLower(extractRecordName(makeAbsoluteName(rr.Name, zone), zone)),
+			typ:  strings.ToUpper(rr.Type),
+		}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		return keys[i].typ < keys[j].typ
+	})
+
+	locks := &p.getClient().rrsets
+	var held []rrsetLockKey
+	var slots []chan struct{}
+	unlock := func() {
+		for i, key := range held {
+			<-slots[i]
+			locks.release(key)
+		}
+	}
+
+	for _, key := range keys {
+		lock := locks.acquire(key)
+		select {
+		case lock.slot <- struct{}{}:
+			held = append(held, key)
+			slots = append(slots, lock.slot)
+		case <-ctx.Done():
+			locks.release(key)
+			unlock()
+			return nil, ctx.Err()
+		}
+	}
+
+	return unlock, nil
+}
@@ -0,0 +1,208 @@
+package dnspod
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// GetZoneNameServers returns the authoritative nameservers DNSPod assigned
+// to the zone, as fully-qualified names. These are the servers the zone
+// must be delegated to at the registrar.
+func (p *Provider) GetZoneNameServers(ctx context.Context, zone string) ([]string, error) {
+	client := p.getClient()
+
+	domainID, err := client.getDomainID(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get domain ID for zone %s: %w", zone, err)
+	}
+
+	info, err := client.getDomainInfo(ctx, domainID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nameservers for zone %s: %w", zone, err)
+	}
+
+	nameServers := make([]string, 0, len(info.DNSPodNS))
+	for _, ns := range info.DNSPodNS {
+		nameServers = append(nameServers, strings.ToLower(strings.TrimSuffix(ns, "."))+".")
+	}
+
+	return nameServers, nil
+}
+
+// DelegationReport compares the NS set published at the parent zone with
+// the nameservers DNSPod assigned to the zone
+type DelegationReport struct {
+	Zone string
+
+	// Expected are the nameservers DNSPod assigned to the zone
+	Expected []string
+
+	// Published are the NS records the parent zone delegates to
+	Published []string
+
+	// Missing are assigned nameservers the parent does not delegate to
+	Missing []string
+
+	// Unexpected are delegated nameservers DNSPod did not assign
+	Unexpected []string
+}
+
+// OK reports whether the zone is delegated exactly to DNSPod's nameservers
+func (r *DelegationReport) OK() bool {
+	return len(r.Missing) == 0 && len(r.Unexpected) == 0
+}
+
+// VerifyDelegation queries the parent zone's nameservers for the zone's
+// delegation and compares it with the nameservers DNSPod assigned. A
+// mismatch is reported in the returned report rather than as an error; an
+// error means the check itself could not be completed.
+func (p *Provider) VerifyDelegation(ctx context.Context, zone string) (*DelegationReport, error) {
+	expected, err := p.GetZoneNameServers(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	published, err := lookupDelegation(ctx, zone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up delegation for zone %s: %w", zone, err)
+	}
+
+	report := &DelegationReport{
+		Zone:      zone,
+		Expected:  expected,
+		Published: published,
+	}
+
+	publishedSet := make(map[string]bool, len(published))
+	for _, ns := range published {
+		publishedSet[ns] = true
+	}
+	expectedSet := make(map[string]bool, len(expected))
+	for _, ns := range expected {
+		expectedSet[ns] = true
+		if !publishedSet[ns] {
+			report.Missing = append(report.Missing, ns)
+		}
+	}
+	for _, ns := range published {
+		if !expectedSet[ns] {
+			report.Unexpected = append(report.Unexpected, ns)
+		}
+	}
+
+	return report, nil
+}
+
+// ListZones returns the zones in the account, from the cached domain list
+// when it is fresh. With SkipInactiveZones, only enabled zones are listed.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	client := p.getClient()
+
+	domains, err := client.getDomains(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	zones := make([]libdns.Zone, 0, len(domains))
+	for _, d := range domains {
+		if p.SkipInactiveZones && d.Status != string(ZoneStatusEnabled) {
+			continue
+		}
+		zones = append(zones, libdns.Zone{Name: d.Name + "."})
+	}
+	return zones, nil
+}
+
+// InvalidateZones drops the cached domain list, so that the next call
+// fetches it again
+func (p *Provider) InvalidateZones() {
+	p.getClient().invalidateDomains()
+}
+
+// ZoneStatus selects zones by their DNSPod status
+type ZoneStatus string
+
+const (
+	ZoneStatusAny     ZoneStatus = ""
+	ZoneStatusEnabled ZoneStatus = "enable"
+	ZoneStatusPaused  ZoneStatus = "pause"
+	ZoneStatusLocked  ZoneStatus = "lock"
+)
+
+// ZoneFilter narrows ListZonesFiltered. GroupID, Keyword and the paused
+// status are sent to Domain.List so that large accounts are filtered
+// server-side; DNSPod has no filter for enabled or locked zones, so those
+// statuses and Grades are applied to the returned zones.
+type ZoneFilter struct {
+	Status  ZoneStatus
+	Grades  []string
+	GroupID string
+
+	// Keyword selects zones whose name contains it
+	Keyword string
+}
+
+// ZoneInfo describes a zone in the DNSPod account
+type ZoneInfo struct {
+	ID      string
+	Name    string
+	Status  string
+	Grade   string
+	GroupID string
+	Records int
+}
+
+// ListZonesFiltered lists the zones in the account that match filter
+func (p *Provider) ListZonesFiltered(ctx context.Context, filter ZoneFilter) ([]ZoneInfo, error) {
+	params := map[string]string{}
+	if filter.Status == ZoneStatusPaused {
+		params["type"] = "ispause"
+	}
+	if filter.GroupID != "" {
+		params["group_id"] = filter.GroupID
+	}
+	if filter.Keyword != "" {
+		params["keyword"] = filter.Keyword
+	}
+
+	domains, err := p.getClient().listDomains(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	var zones []ZoneInfo
+	for _, d := range domains {
+		if filter.Status != ZoneStatusAny && filter.Status != ZoneStatusPaused && d.Status != string(filter.Status) {
+			continue
+		}
+		if len(filter.Grades) > 0 && !containsFold(filter.Grades, d.Grade) {
+			continue
+		}
+
+		records, _ := strconv.Atoi(d.Records)
+		zones = append(zones, ZoneInfo{
+			ID:      string(d.ID),
+			Name:    d.Name,
+			Status:  d.Status,
+			Grade:   d.Grade,
+			GroupID: d.GroupID,
+			Records: records,
+		})
+	}
+
+	return zones, nil
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package dnspod
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// zoneFilterTests are run against both APIs. The backends hold an enabled
+// zone a, a paused zone b and a locked zone c; the paused filter is applied
+// by the backend.
+var zoneFilterTests = []struct {
+	name   string
+	filter ZoneFilter
+	want   []string
+	sent   map[string]string
+}{
+	{"any", ZoneFilter{}, []string{"a.com", "b.com", "c.com"}, nil},
+	{"enabled", ZoneFilter{Status: ZoneStatusEnabled}, []string{"a.com"}, nil},
+	{"paused", ZoneFilter{Status: ZoneStatusPaused}, []string{"b.com"}, map[string]string{"type": "ispause"}},
+	{"locked", ZoneFilter{Status: ZoneStatusLocked}, []string{"c.com"}, nil},
+	{"grade", ZoneFilter{Grades: []string{"dp_plus"}}, []string{"c.com"}, nil},
+	{"group and keyword", ZoneFilter{GroupID: "2", Keyword: "com"}, []string{"a.com", "b.com", "c.com"}, map[string]string{"group_id": "2", "keyword": "com"}},
+}
+
+func zoneNames(zones []ZoneInfo) []string {
+	var names []string
+	for _, zone := range zones {
+		names = append(names, zone.Name)
+	}
+	return names
+}
+
+func TestListZonesFiltered(t *testing.T) {
+	for _, tt := range zoneFilterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := map[string]string{}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				r.ParseForm()
+				for _, key := range []string{"type", "group_id", "keyword"} {
+					if value := r.PostForm.Get(key); value != "" {
+						sent[key] = value
+					}
+				}
+				domains := []map[string]any{
+					{"id": 1, "name": "a.com", "status": "enable", "grade": "DP_Free"},
+					{"id": 2, "name": "b.com", "status": "pause", "grade": "DP_Free"},
+					{"id": 3, "name": "c.com", "status": "lock", "grade": "DP_Plus"},
+				}
+				if r.PostForm.Get("type") == "ispause" {
+					domains = domains[1:2]
+				}
+				json.NewEncoder(w).Encode(map[string]any{
+					"status":  map[string]string{"code": "1", "message": "ok"},
+					"info":    map[string]any{"domain_total": len(domains)},
+					"domains": domains,
+				})
+			}))
+			t.Cleanup(server.Close)
+
+			p := &Provider{LoginToken: "1,token", Endpoint: server.URL}
+			zones, err := p.ListZonesFiltered(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := zoneNames(zones); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got zones %v, want %v", got, tt.want)
+			}
+			if tt.sent == nil {
+				tt.sent = map[string]string{}
+			}
+			if !reflect.DeepEqual(sent, tt.sent) {
+				t.Errorf("sent %v, want %v", sent, tt.sent)
+			}
+		})
+	}
+}
+
+func TestListZonesFilteredV3(t *testing.T) {
+	for _, tt := range zoneFilterTests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := map[string]string{}
+			server := newV3Server(t, func(action string, payload map[string]any) any {
+				if action != "DescribeDomainList" {
+					t.Errorf("unexpected action %s", action)
+				}
+				for _, key := range []string{"Type", "GroupId", "Keyword"} {
+					if value, ok := payload[key]; ok {
+						b, _ := json.Marshal(value)
+						sent[key] = strings.Trim(string(b), `"`)
+					}
+				}
+				domains := []map[string]any{
+					{"DomainId": 1, "Name": "a.com", "Status": "ENABLE", "Grade": "DP_FREE"},
+					{"DomainId": 2, "Name": "b.com", "Status": "PAUSE", "Grade": "DP_FREE"},
+					{"DomainId": 3, "Name": "c.com", "Status": "LOCK", "Grade": "DP_PLUS"},
+				}
+				if payload["Type"] == "PAUSE" {
+					domains = domains[1:2]
+				}
+				return v3DomainList(domains...)
+			})
+
+			p := &Provider{SecretID: "id", SecretKey: "key", Endpoint: server.URL}
+			zones, err := p.ListZonesFiltered(context.Background(), tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := zoneNames(zones); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got zones %v, want %v", got, tt.want)
+			}
+
+			want := map[string]string{}
+			v3Names := map[string]string{"type": "Type", "group_id": "GroupId", "keyword": "Keyword"}
+			for key, value := range tt.sent {
+				if key == "type" {
+					value = v3DomainTypes[value]
+				}
+				want[v3Names[key]] = value
+			}
+			if !reflect.DeepEqual(sent, want) {
+				t.Errorf("sent %v, want %v", sent, want)
+			}
+		})
+	}
+}